@@ -2,28 +2,44 @@ package amqp
 
 import (
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/streadway/amqp"
 )
 
 type ChannelInterface interface {
+	Cancel(consumer string, noWait bool) error
 	Close() error
+	Confirm(noWait bool) error
 	Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan DeliveryInterface, error)
+	ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error
 	NotifyClose(c chan *amqp.Error) chan *amqp.Error
+	NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation
+	NotifyReturn(c chan amqp.Return) chan amqp.Return
 	Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error
+	Qos(prefetchCount, prefetchSize int, global bool) error
 	QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error
 	QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error)
+	QueueUnbind(name, key, exchange string, args amqp.Table) error
 }
 
 type Channel struct {
 	channel *amqp.Channel
 }
 
+func (ch *Channel) Cancel(consumer string, noWait bool) error {
+	return ch.channel.Cancel(consumer, noWait)
+}
+
 func (ch *Channel) Close() error {
 	return ch.channel.Close()
 }
 
+func (ch *Channel) Confirm(noWait bool) error {
+	return ch.channel.Confirm(noWait)
+}
+
 func (ch *Channel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan DeliveryInterface, error) {
 	deliveries, err := ch.channel.Consume(queue, consumer, autoAck, exclusive, noLocal, noWait, args)
 	if err != nil {
@@ -32,6 +48,10 @@ func (ch *Channel) Consume(queue, consumer string, autoAck, exclusive, noLocal,
 
 	deliveriesInterfaceChan := make(chan DeliveryInterface)
 	go func() {
+		// Closed along with the deliveries, once the consumer has been cancelled
+		// and the deliveries sent ahead of the cancel have been read
+		defer close(deliveriesInterfaceChan)
+
 		for d := range deliveries {
 			deliveriesInterfaceChan <- Delivery{d}
 		}
@@ -40,14 +60,30 @@ func (ch *Channel) Consume(queue, consumer string, autoAck, exclusive, noLocal,
 	return (<-chan DeliveryInterface)(deliveriesInterfaceChan), nil
 }
 
+func (ch *Channel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	return ch.channel.ExchangeDeclare(name, kind, durable, autoDelete, internal, noWait, args)
+}
+
 func (ch *Channel) NotifyClose(c chan *amqp.Error) chan *amqp.Error {
 	return ch.channel.NotifyClose(c)
 }
 
+func (ch *Channel) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	return ch.channel.NotifyPublish(confirm)
+}
+
+func (ch *Channel) NotifyReturn(c chan amqp.Return) chan amqp.Return {
+	return ch.channel.NotifyReturn(c)
+}
+
 func (ch *Channel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
 	return ch.channel.Publish(exchange, key, mandatory, immediate, msg)
 }
 
+func (ch *Channel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	return ch.channel.Qos(prefetchCount, prefetchSize, global)
+}
+
 func (ch *Channel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
 	return ch.channel.QueueBind(name, key, exchange, noWait, args)
 }
@@ -56,6 +92,10 @@ func (ch *Channel) QueueDeclare(name string, durable, autoDelete, exclusive, noW
 	return ch.channel.QueueDeclare(name, durable, autoDelete, exclusive, noWait, args)
 }
 
+func (ch *Channel) QueueUnbind(name, key, exchange string, args amqp.Table) error {
+	return ch.channel.QueueUnbind(name, key, exchange, args)
+}
+
 // MOCKS
 
 type mockConsume struct {
@@ -68,6 +108,16 @@ type mockConsume struct {
 	args      amqp.Table
 }
 
+type mockExchangeDeclare struct {
+	name       string
+	kind       string
+	durable    bool
+	autoDelete bool
+	internal   bool
+	noWait     bool
+	args       amqp.Table
+}
+
 type mockPublish struct {
 	exchange  string
 	key       string
@@ -76,6 +126,12 @@ type mockPublish struct {
 	msg       amqp.Publishing
 }
 
+type mockQos struct {
+	prefetchCount int
+	prefetchSize  int
+	global        bool
+}
+
 type mockQueueBind struct {
 	name     string
 	key      string
@@ -84,6 +140,13 @@ type mockQueueBind struct {
 	args     amqp.Table
 }
 
+type mockQueueUnbind struct {
+	name     string
+	key      string
+	exchange string
+	args     amqp.Table
+}
+
 type mockQueueDeclare struct {
 	name       string
 	durable    bool
@@ -94,20 +157,63 @@ type mockQueueDeclare struct {
 }
 
 type mockChannel struct {
-	notifyCloses      []chan *amqp.Error
-	mockConsumes      []mockConsume
-	mockPublishes     []mockPublish
-	mockQueueBinds    []mockQueueBind
-	mockQueueDeclares []mockQueueDeclare
+	mu sync.Mutex
+
+	notifyCloses         []chan *amqp.Error
+	mockCancels          []string
+	notifyPublishes      []chan amqp.Confirmation
+	notifyReturns        []chan amqp.Return
+	mockConsumes         []mockConsume
+	mockExchangeDeclares []mockExchangeDeclare
+	mockPublishes        []mockPublish
+	mockQoses            []mockQos
+	mockQueueBinds       []mockQueueBind
+	mockQueueDeclares    []mockQueueDeclare
+	mockQueueUnbinds     []mockQueueUnbind
 
 	closed             bool
+	consumerCancelled  bool
+	confirmMode        bool
+	deliveryTag        uint64
 	mockDeliveries     chan DeliveryInterface
+	publishConfirms    []string
+	unroutableKeys     map[string]bool
 	publishErrors      []error
 	queueBindErrors    []error
 	queueDeclareErrors []error
 }
 
+// Much like the broker, the deliveries are closed once the consumer has been
+// cancelled
+func (ch *mockChannel) Cancel(consumer string, noWait bool) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.closed {
+		return errors.New("mock channel has been closed")
+	}
+
+	ch.mockCancels = append(ch.mockCancels, consumer)
+
+	if !ch.consumerCancelled {
+		ch.consumerCancelled = true
+		close(ch.mockDeliveries)
+	}
+
+	return nil
+}
+
+func (ch *mockChannel) getCancels() []string {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	return ch.mockCancels
+}
+
 func (ch *mockChannel) Close() error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
 	if !ch.closed {
 		ch.closed = true
 
@@ -115,13 +221,48 @@ func (ch *mockChannel) Close() error {
 			close(ch.notifyCloses[i])
 		}
 
+		// Like the library, the confirmations and returns are closed along
+		// with the channel
+		for i := range ch.notifyPublishes {
+			close(ch.notifyPublishes[i])
+		}
+
+		for i := range ch.notifyReturns {
+			close(ch.notifyReturns[i])
+		}
+
 		ch.notifyCloses = []chan *amqp.Error{}
+		ch.notifyPublishes = []chan amqp.Confirmation{}
+		ch.notifyReturns = []chan amqp.Return{}
 	}
 
 	return nil
 }
 
+func (ch *mockChannel) isClosed() bool {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	return ch.closed
+}
+
+func (ch *mockChannel) Confirm(noWait bool) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.closed {
+		return errors.New("mock channel has been closed")
+	}
+
+	ch.confirmMode = true
+
+	return nil
+}
+
 func (ch *mockChannel) Consume(queue, consumer string, autoAck, exclusive, noLocal, noWait bool, args amqp.Table) (<-chan DeliveryInterface, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
 	if ch.closed {
 		return nil, errors.New("mock channel has been closed")
 	}
@@ -139,38 +280,122 @@ func (ch *mockChannel) Consume(queue, consumer string, autoAck, exclusive, noLoc
 	return ch.mockDeliveries, nil
 }
 
+func (ch *mockChannel) ExchangeDeclare(name, kind string, durable, autoDelete, internal, noWait bool, args amqp.Table) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.closed {
+		return errors.New("mock channel has been closed")
+	}
+
+	ch.mockExchangeDeclares = append(ch.mockExchangeDeclares, mockExchangeDeclare{
+		name:       name,
+		kind:       kind,
+		durable:    durable,
+		autoDelete: autoDelete,
+		internal:   internal,
+		noWait:     noWait,
+		args:       args,
+	})
+
+	return nil
+}
+
 func (ch *mockChannel) errorOnPublish(err error) *mockChannel {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
 	ch.publishErrors = append(ch.publishErrors, err)
 
 	return ch
 }
 
+// The next publish is nacked by the mock broker
+func (ch *mockChannel) nackOnPublish() *mockChannel {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.publishConfirms = append(ch.publishConfirms, "nack")
+
+	return ch
+}
+
+// The next publish never gets a confirmation from the mock broker
+func (ch *mockChannel) dropConfirmOnPublish() *mockChannel {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.publishConfirms = append(ch.publishConfirms, "drop")
+
+	return ch
+}
+
 func (ch *mockChannel) errorOnQueueBind(err error) *mockChannel {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
 	ch.queueBindErrors = append(ch.queueBindErrors, err)
 
 	return ch
 }
 
 func (ch *mockChannel) errorOnQueueDeclare(err error) *mockChannel {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
 	ch.queueDeclareErrors = append(ch.queueDeclareErrors, err)
 
 	return ch
 }
 
 func (ch *mockChannel) NotifyClose(c chan *amqp.Error) chan *amqp.Error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
 	ch.notifyCloses = append(ch.notifyCloses, c)
 
 	return c
 }
 
+func (ch *mockChannel) NotifyPublish(confirm chan amqp.Confirmation) chan amqp.Confirmation {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.notifyPublishes = append(ch.notifyPublishes, confirm)
+
+	return confirm
+}
+
+func (ch *mockChannel) NotifyReturn(c chan amqp.Return) chan amqp.Return {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.notifyReturns = append(ch.notifyReturns, c)
+
+	return c
+}
+
+// Mandatory publishes to the key are returned by the mock broker
+func (ch *mockChannel) returnOnPublish(key string) *mockChannel {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	ch.unroutableKeys[key] = true
+
+	return ch
+}
+
 func (ch *mockChannel) Publish(exchange, key string, mandatory, immediate bool, msg amqp.Publishing) error {
-	if ch.closed {
+	if ch.isClosed() {
 		return errors.New("mock channel has been closed")
 	}
 
-	// Simulate a tiny amount of latency
+	// Simulate a tiny amount of latency, without holding up the other calls
 	time.Sleep(1 * time.Millisecond)
 
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
 	if len(ch.publishErrors) > 0 {
 		publishError := ch.publishErrors[0]
 
@@ -187,10 +412,72 @@ func (ch *mockChannel) Publish(exchange, key string, mandatory, immediate bool,
 		msg:       msg,
 	})
 
+	// Like the broker, the return is sent ahead of the confirmation
+	if mandatory && ch.unroutableKeys[key] {
+		for i := range ch.notifyReturns {
+			ch.notifyReturns[i] <- amqp.Return{
+				ReplyCode:  amqp.NoRoute,
+				ReplyText:  "NO_ROUTE",
+				Exchange:   exchange,
+				RoutingKey: key,
+				MessageId:  msg.MessageId,
+			}
+		}
+	}
+
+	if ch.confirmMode {
+		ch.confirm()
+	}
+
+	return nil
+}
+
+func (ch *mockChannel) confirm() {
+	ch.deliveryTag += 1
+
+	confirmation := amqp.Confirmation{
+		DeliveryTag: ch.deliveryTag,
+		Ack:         true,
+	}
+
+	if len(ch.publishConfirms) > 0 {
+		publishConfirm := ch.publishConfirms[0]
+
+		ch.publishConfirms = ch.publishConfirms[1:]
+
+		if publishConfirm == "drop" {
+			return
+		}
+
+		confirmation.Ack = publishConfirm != "nack"
+	}
+
+	for i := range ch.notifyPublishes {
+		ch.notifyPublishes[i] <- confirmation
+	}
+}
+
+func (ch *mockChannel) Qos(prefetchCount, prefetchSize int, global bool) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.closed {
+		return errors.New("mock channel has been closed")
+	}
+
+	ch.mockQoses = append(ch.mockQoses, mockQos{
+		prefetchCount: prefetchCount,
+		prefetchSize:  prefetchSize,
+		global:        global,
+	})
+
 	return nil
 }
 
 func (ch *mockChannel) QueueBind(name, key, exchange string, noWait bool, args amqp.Table) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
 	if ch.closed {
 		return errors.New("mock channel has been closed")
 	}
@@ -215,6 +502,9 @@ func (ch *mockChannel) QueueBind(name, key, exchange string, noWait bool, args a
 }
 
 func (ch *mockChannel) QueueDeclare(name string, durable, autoDelete, exclusive, noWait bool, args amqp.Table) (amqp.Queue, error) {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
 	if ch.closed {
 		return amqp.Queue{}, errors.New("mock channel has been closed")
 	}
@@ -239,14 +529,39 @@ func (ch *mockChannel) QueueDeclare(name string, durable, autoDelete, exclusive,
 	return amqp.Queue{}, nil
 }
 
+func (ch *mockChannel) QueueUnbind(name, key, exchange string, args amqp.Table) error {
+	ch.mu.Lock()
+	defer ch.mu.Unlock()
+
+	if ch.closed {
+		return errors.New("mock channel has been closed")
+	}
+
+	ch.mockQueueUnbinds = append(ch.mockQueueUnbinds, mockQueueUnbind{
+		name:     name,
+		key:      key,
+		exchange: exchange,
+		args:     args,
+	})
+
+	return nil
+}
+
 func newMockChannel() *mockChannel {
 	return &mockChannel{
-		notifyCloses:      []chan *amqp.Error{},
-		mockConsumes:      []mockConsume{},
-		mockPublishes:     []mockPublish{},
-		mockQueueBinds:    []mockQueueBind{},
-		mockQueueDeclares: []mockQueueDeclare{},
+		notifyCloses:         []chan *amqp.Error{},
+		mockCancels:          []string{},
+		notifyPublishes:      []chan amqp.Confirmation{},
+		notifyReturns:        []chan amqp.Return{},
+		mockConsumes:         []mockConsume{},
+		mockExchangeDeclares: []mockExchangeDeclare{},
+		mockPublishes:        []mockPublish{},
+		mockQoses:            []mockQos{},
+		mockQueueBinds:       []mockQueueBind{},
+		mockQueueDeclares:    []mockQueueDeclare{},
+		mockQueueUnbinds:     []mockQueueUnbind{},
 
 		mockDeliveries: make(chan DeliveryInterface),
+		unroutableKeys: map[string]bool{},
 	}
 }
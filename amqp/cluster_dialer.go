@@ -0,0 +1,185 @@
+package amqp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/streadway/amqp"
+)
+
+// How long a node that failed is skipped unless the dialer is told otherwise
+const DEFAULT_NODE_COOLDOWN = 5 * time.Second
+
+var NoClusterNodeAvailable = errors.New("every node of the cluster is cooling down after a failure")
+
+type ClusterDialerOption func(*ClusterDialer)
+
+// WithShuffledNodes shuffles the nodes once up front, so that the clients of a
+// cluster spread their connections over its nodes instead of all starting
+// with the first one.
+func WithShuffledNodes() ClusterDialerOption {
+	return func(d *ClusterDialer) {
+		rand.Shuffle(len(d.nodes), func(i, j int) {
+			d.nodes[i], d.nodes[j] = d.nodes[j], d.nodes[i]
+		})
+	}
+}
+
+// WithNodeCooldown sets how long a node that failed is skipped for
+func WithNodeCooldown(cooldown time.Duration) ClusterDialerOption {
+	return func(d *ClusterDialer) {
+		d.cooldown = cooldown
+	}
+}
+
+type clusterNode struct {
+	dialerInterface DialerInterface
+	name            string
+	cooldownUntil   time.Time
+}
+
+// ClusterDialer treats several urls as the nodes of a single cluster rather
+// than as independent brokers. It keeps a single connection to one node and
+// dials the next one that isn't cooling down when it can't be reached or the
+// connection is lost, starting with the last node that worked.
+type ClusterDialer struct {
+	nodes    []*clusterNode
+	cooldown time.Duration
+
+	mu         sync.Mutex
+	connection ConnectionInterface
+	current    int
+}
+
+func (d *ClusterDialer) Dial() (ConnectionInterface, error) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.connection != nil {
+		return d.connection, nil
+	}
+
+	if len(d.nodes) <= 0 {
+		return nil, errors.New("No nodes have been declared in the cluster dialer")
+	}
+
+	var dialErr error
+
+	for i := 0; i < len(d.nodes); i++ {
+		offset := (d.current + i) % len(d.nodes)
+		node := d.nodes[offset]
+
+		if time.Now().Before(node.cooldownUntil) {
+			continue
+		}
+
+		connection, err := node.dialerInterface.Dial()
+		if err != nil {
+			logger.WithFields(logrus.Fields{
+				"node":     node.name,
+				"cooldown": d.cooldown.String(),
+			}).WithError(err).Warn("Failed to dial a node of the cluster")
+
+			node.cooldownUntil = time.Now().Add(d.cooldown)
+			dialErr = err
+			continue
+		}
+
+		d.connection = connection
+		d.current = offset
+
+		go d.watch(connection, connection.NotifyClose(make(chan *amqp.Error, 1)), node)
+
+		return connection, nil
+	}
+
+	if dialErr != nil {
+		return nil, dialErr
+	}
+
+	return nil, NoClusterNodeAvailable
+}
+
+// Forgets the connection once it's closed so that the next dial fails over, a
+// node that closed on us unexpectedly is cooled down as well.
+func (d *ClusterDialer) watch(connection ConnectionInterface, closed chan *amqp.Error, node *clusterNode) {
+	err := <-closed
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if d.connection == connection {
+		d.connection = nil
+	}
+
+	if err != nil {
+		logger.WithField("node", node.name).WithField("reason", err.Error()).Warn("Lost the connection to a node of the cluster, failing over")
+
+		node.cooldownUntil = time.Now().Add(d.cooldown)
+	}
+}
+
+func newClusterDialer(names []string, dialerInterfaces []DialerInterface, options []ClusterDialerOption) *ClusterDialer {
+	clusterDialer := &ClusterDialer{
+		cooldown: DEFAULT_NODE_COOLDOWN,
+	}
+
+	for i := range dialerInterfaces {
+		clusterDialer.nodes = append(clusterDialer.nodes, &clusterNode{
+			dialerInterface: dialerInterfaces[i],
+			name:            names[i],
+		})
+	}
+
+	for _, option := range options {
+		option(clusterDialer)
+	}
+
+	return clusterDialer
+}
+
+// NewClusterDialer dials the nodes in the order of their urls unless they are
+// shuffled.
+func NewClusterDialer(urls []string, options ...ClusterDialerOption) *ClusterDialer {
+	dialerInterfaces := []DialerInterface{}
+	names := []string{}
+
+	for i := range urls {
+		dialerInterfaces = append(dialerInterfaces, NewDialer(urls[i]))
+
+		names = append(names, nodeName(urls[i]))
+	}
+
+	return newClusterDialer(names, dialerInterfaces, options)
+}
+
+func NewClusterDialerWithConfigs(dialerConfigs []DialerConfig, options ...ClusterDialerOption) (*ClusterDialer, error) {
+	dialerInterfaces := []DialerInterface{}
+	names := []string{}
+
+	for i := range dialerConfigs {
+		dialer, err := NewDialerWithConfig(dialerConfigs[i])
+		if err != nil {
+			return nil, err
+		}
+
+		dialerInterfaces = append(dialerInterfaces, dialer)
+		names = append(names, nodeName(dialerConfigs[i].URL))
+	}
+
+	return newClusterDialer(names, dialerInterfaces, options), nil
+}
+
+// The urls carry credentials, only the host and port are fit for the logs
+func nodeName(url string) string {
+	uri, err := amqp.ParseURI(url)
+	if err != nil {
+		return "unknown"
+	}
+
+	return fmt.Sprintf("%s:%d", uri.Host, uri.Port)
+}
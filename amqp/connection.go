@@ -2,6 +2,7 @@ package amqp
 
 import (
 	"errors"
+	"sync"
 
 	"github.com/streadway/amqp"
 )
@@ -38,6 +39,7 @@ func (c *Connection) NotifyClose(ch chan *amqp.Error) chan *amqp.Error {
 // MOCKS
 
 type mockConnection struct {
+	mu             sync.Mutex
 	channel        *mockChannel
 	closeNotifiers []chan *amqp.Error
 	closed         bool
@@ -46,6 +48,9 @@ type mockConnection struct {
 func (c *mockConnection) Close() error {
 	c.channel.Close()
 
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	for i := range c.closeNotifiers {
 		close(c.closeNotifiers[i])
 	}
@@ -56,7 +61,24 @@ func (c *mockConnection) Close() error {
 	return nil
 }
 
+// Closes the connection the way the broker does, handing the error to every
+// notifier first
+func (c *mockConnection) closeWithError(err *amqp.Error) {
+	c.mu.Lock()
+	closeNotifiers := c.closeNotifiers
+	c.mu.Unlock()
+
+	for i := range closeNotifiers {
+		closeNotifiers[i] <- err
+	}
+
+	c.Close()
+}
+
 func (c *mockConnection) GetChannelInterface() (ChannelInterface, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	if c.closed {
 		return nil, errors.New("mock connector has been closed")
 	}
@@ -65,6 +87,9 @@ func (c *mockConnection) GetChannelInterface() (ChannelInterface, error) {
 }
 
 func (c *mockConnection) NotifyClose(ch chan *amqp.Error) chan *amqp.Error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
 	c.closeNotifiers = append(c.closeNotifiers, ch)
 
 	return ch
@@ -1,6 +1,7 @@
 package amqp
 
 import (
+	"sync"
 	"time"
 
 	"github.com/streadway/amqp"
@@ -96,6 +97,8 @@ type mockDelivery struct {
 
 	rejected       bool
 	rejectRequeued bool
+
+	mu sync.Mutex
 }
 
 func (d *mockDelivery) GetAcknowledger() amqp.Acknowledger { return d.Acknowledger }
@@ -121,6 +124,9 @@ func (d *mockDelivery) GetRoutingKey() string              { return d.RoutingKey
 func (d *mockDelivery) GetBody() []byte                    { return d.Body }
 
 func (d *mockDelivery) Ack(multiple bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	d.acked = true
 	d.ackMultiple = multiple
 
@@ -128,6 +134,9 @@ func (d *mockDelivery) Ack(multiple bool) error {
 }
 
 func (d *mockDelivery) Nack(multiple, requeue bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	d.nacked = true
 	d.nackMultiple = multiple
 	d.nackRequeue = requeue
@@ -136,8 +145,25 @@ func (d *mockDelivery) Nack(multiple, requeue bool) error {
 }
 
 func (d *mockDelivery) Reject(requeue bool) error {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	d.rejected = true
 	d.rejectRequeued = requeue
 
 	return nil
 }
+
+func (d *mockDelivery) isAcked() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.acked
+}
+
+func (d *mockDelivery) isRejected() bool {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.rejected
+}
@@ -12,11 +12,19 @@ type DialerInterface interface {
 }
 
 type Dialer struct {
-	url string
+	url    string
+	config *amqp.Config
 }
 
 func (d *Dialer) Dial() (ConnectionInterface, error) {
-	connection, err := amqp.Dial(d.url)
+	var connection *amqp.Connection
+	var err error
+
+	if d.config != nil {
+		connection, err = amqp.DialConfig(d.url, *d.config)
+	} else {
+		connection, err = amqp.Dial(d.url)
+	}
 	if err != nil {
 		return nil, err
 	}
@@ -30,6 +38,20 @@ func NewDialer(url string) *Dialer {
 	return &Dialer{url: url}
 }
 
+// NewDialerWithConfig loads the config's certificates up front so that a bad
+// TLS setup is reported right away rather than on every dial.
+func NewDialerWithConfig(dialerConfig DialerConfig) (*Dialer, error) {
+	config, err := dialerConfig.amqpConfig()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Dialer{
+		url:    dialerConfig.URL,
+		config: &config,
+	}, nil
+}
+
 func NewDialers(urls []string) []*Dialer {
 	amqpDialers := []*Dialer{}
 
@@ -40,10 +62,26 @@ func NewDialers(urls []string) []*Dialer {
 	return amqpDialers
 }
 
+type CachingDialerOption func(*CachingDialer)
+
+// WithDialerReconnectPolicy holds the dials back while the broker is
+// unavailable, DefaultReconnectPolicy otherwise. Dials made too early fail
+// with ReconnectPending.
+func WithDialerReconnectPolicy(policy ReconnectPolicy) CachingDialerOption {
+	return func(d *CachingDialer) {
+		d.reconnector = newReconnector(policy)
+	}
+}
+
 type CachingDialer struct {
-	dialer     *Dialer
-	connection ConnectionInterface
-	mu         sync.Mutex
+	dialer      *Dialer
+	connection  ConnectionInterface
+	reconnector *reconnector
+	mu          sync.Mutex
+}
+
+func (d *CachingDialer) ConnectionState() ConnectionState {
+	return d.reconnector.ConnectionState()
 }
 
 func (d *CachingDialer) Dial() (ConnectionInterface, error) {
@@ -54,12 +92,18 @@ func (d *CachingDialer) Dial() (ConnectionInterface, error) {
 		return d.connection, nil
 	}
 
+	if err := d.reconnector.ready(); err != nil {
+		return nil, err
+	}
+
 	connection, err := d.dialer.Dial()
 	if err != nil {
+		d.reconnector.failed()
 		return nil, err
 	}
 
 	d.connection = connection
+	d.reconnector.connected()
 
 	go d.keepAlive()
 
@@ -76,19 +120,41 @@ func (d *CachingDialer) keepAlive() {
 	d.mu.Lock()
 	d.connection = nil
 	d.mu.Unlock()
+
+	d.reconnector.lost()
+}
+
+func newCachingDialer(dialer *Dialer, options []CachingDialerOption) *CachingDialer {
+	cachingDialer := &CachingDialer{
+		dialer:      dialer,
+		reconnector: newReconnector(DefaultReconnectPolicy),
+	}
+
+	for _, option := range options {
+		option(cachingDialer)
+	}
+
+	return cachingDialer
 }
 
-func NewCachingDialer(url string) *CachingDialer {
-	return &CachingDialer{
-		dialer: NewDialer(url),
+func NewCachingDialer(url string, options ...CachingDialerOption) *CachingDialer {
+	return newCachingDialer(NewDialer(url), options)
+}
+
+func NewCachingDialerWithConfig(dialerConfig DialerConfig, options ...CachingDialerOption) (*CachingDialer, error) {
+	dialer, err := NewDialerWithConfig(dialerConfig)
+	if err != nil {
+		return nil, err
 	}
+
+	return newCachingDialer(dialer, options), nil
 }
 
-func NewCachingDialers(urls []string) []*CachingDialer {
+func NewCachingDialers(urls []string, options ...CachingDialerOption) []*CachingDialer {
 	CachingamqpDialers := []*CachingDialer{}
 
 	for i := range urls {
-		CachingamqpDialers = append(CachingamqpDialers, NewCachingDialer(urls[i]))
+		CachingamqpDialers = append(CachingamqpDialers, NewCachingDialer(urls[i], options...))
 	}
 
 	return CachingamqpDialers
@@ -97,6 +163,7 @@ func NewCachingDialers(urls []string) []*CachingDialer {
 // MOCKS
 
 type mockDialer struct {
+	mu         sync.Mutex
 	connection *mockConnection
 	connected  chan interface{}
 	dialErr    error
@@ -104,10 +171,15 @@ type mockDialer struct {
 }
 
 func (d *mockDialer) Dial() (ConnectionInterface, error) {
+	d.mu.Lock()
 	d.totalDials += 1
+	d.mu.Unlock()
 
 	time.Sleep(10 * time.Millisecond)
 
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
 	d.connection = newMockConnection()
 
 	if d.connected != nil {
@@ -118,6 +190,28 @@ func (d *mockDialer) Dial() (ConnectionInterface, error) {
 	return d.connection, d.dialErr
 }
 
+func (d *mockDialer) getConnection() *mockConnection {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.connection
+}
+
+// Closed once the next dial is done
+func (d *mockDialer) getConnected() chan interface{} {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.connected
+}
+
+func (d *mockDialer) getTotalDials() int {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	return d.totalDials
+}
+
 func newMockDialer() *mockDialer {
 	return &mockDialer{
 		connected: make(chan interface{}),
@@ -0,0 +1,191 @@
+package amqp
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/microplatform-io/platform"
+	"github.com/streadway/amqp"
+)
+
+// The heartbeat interval used unless the config asks for another one, the same
+// one amqp.Dial uses
+const DEFAULT_HEARTBEAT = 10 * time.Second
+
+// DialerConfig describes how to connect to a broker beyond its url: the TLS
+// settings, the heartbeat interval and how the connection shows up in the
+// broker's management UI.
+type DialerConfig struct {
+	URL string
+
+	// Overrides the vhost of the url when set
+	Vhost string
+
+	// Defaults to DEFAULT_HEARTBEAT, a negative interval uses the broker's
+	Heartbeat time.Duration
+
+	// Shown in the broker's management UI, see ConnectionName
+	ConnectionName string
+
+	// Connects with amqps, which any of the other TLS settings imply as well
+	TLS bool
+
+	// PEM encoded client certificate and key, for brokers that verify their peers
+	CertFile string
+	KeyFile  string
+
+	// PEM encoded CA bundle the broker's certificate is verified against, the
+	// system's roots are used otherwise
+	CAFile string
+
+	// The name the broker's certificate is verified against, the url's host
+	// otherwise
+	ServerName string
+}
+
+func (c DialerConfig) usesTLS() bool {
+	return c.TLS || c.CertFile != "" || c.KeyFile != "" || c.CAFile != "" || c.ServerName != ""
+}
+
+func (c DialerConfig) tlsConfig() (*tls.Config, error) {
+	if !c.usesTLS() {
+		return nil, nil
+	}
+
+	tlsConfig := &tls.Config{
+		ServerName: c.ServerName,
+	}
+
+	if c.CAFile != "" {
+		ca, err := ioutil.ReadFile(c.CAFile)
+		if err != nil {
+			return nil, err
+		}
+
+		rootCAs := x509.NewCertPool()
+		if !rootCAs.AppendCertsFromPEM(ca) {
+			return nil, fmt.Errorf("No certificates could be parsed from %s", c.CAFile)
+		}
+
+		tlsConfig.RootCAs = rootCAs
+	}
+
+	if c.CertFile != "" || c.KeyFile != "" {
+		certificate, err := tls.LoadX509KeyPair(c.CertFile, c.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+
+		tlsConfig.Certificates = []tls.Certificate{certificate}
+	}
+
+	return tlsConfig, nil
+}
+
+func (c DialerConfig) amqpConfig() (amqp.Config, error) {
+	if c.usesTLS() && !strings.HasPrefix(c.URL, "amqps://") {
+		return amqp.Config{}, errors.New("TLS settings need an amqps url")
+	}
+
+	tlsConfig, err := c.tlsConfig()
+	if err != nil {
+		return amqp.Config{}, err
+	}
+
+	heartbeat := c.Heartbeat
+	if heartbeat == 0 {
+		heartbeat = DEFAULT_HEARTBEAT
+	} else if heartbeat < 0 {
+		heartbeat = 0
+	}
+
+	config := amqp.Config{
+		Vhost:           c.Vhost,
+		Heartbeat:       heartbeat,
+		TLSClientConfig: tlsConfig,
+		Locale:          "en_US",
+	}
+
+	if c.ConnectionName != "" {
+		config.Properties = amqp.Table{
+			"product":         "platform",
+			"connection_name": c.ConnectionName,
+		}
+	}
+
+	return config, nil
+}
+
+// ConnectionName identifies this process's connections in the broker's
+// management UI, as the service name and the host it runs on.
+func ConnectionName(serviceName string) string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "unknown"
+	}
+
+	return serviceName + "@" + hostname
+}
+
+// Orders the indices numerically, so that "10" comes after "2". Indices that
+// aren't numbers come after the ones that are, in text order.
+func indexLess(a, b string) bool {
+	aNumber, aErr := strconv.Atoi(a)
+	bNumber, bErr := strconv.Atoi(b)
+
+	switch {
+	case aErr == nil && bErr == nil:
+		return aNumber < bNumber
+	case aErr == nil || bErr == nil:
+		return aErr == nil
+	default:
+		return a < b
+	}
+}
+
+// NewDialerConfigs builds a dialer config for every service config a config
+// manager discovered, ordered by their index. Everything but the url comes
+// from the base config.
+func NewDialerConfigs(serviceConfigs map[string]*platform.ServiceConfig, base DialerConfig) []DialerConfig {
+	indices := []string{}
+	for index := range serviceConfigs {
+		indices = append(indices, index)
+	}
+
+	sort.Slice(indices, func(i, j int) bool {
+		return indexLess(indices[i], indices[j])
+	})
+
+	scheme := "amqp"
+	if base.usesTLS() {
+		scheme = "amqps"
+	}
+
+	dialerConfigs := []DialerConfig{}
+
+	for _, index := range indices {
+		serviceConfig := serviceConfigs[index]
+
+		dialerConfig := base
+		dialerConfig.URL = (&url.URL{
+			Scheme: scheme,
+			User:   url.UserPassword(serviceConfig.User, serviceConfig.Pass),
+			Host:   net.JoinHostPort(serviceConfig.Addr, serviceConfig.Port),
+			Path:   "/",
+		}).String()
+
+		dialerConfigs = append(dialerConfigs, dialerConfig)
+	}
+
+	return dialerConfigs
+}
@@ -0,0 +1,140 @@
+package amqp
+
+import (
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/microplatform-io/platform"
+	"github.com/streadway/amqp"
+)
+
+// The exchange publishers and subscribers use unless they are told otherwise
+const DEFAULT_EXCHANGE = "amq.topic"
+
+// Policies for a queue that has reached its max length
+const (
+	OVERFLOW_DROP_HEAD          = "drop-head"
+	OVERFLOW_REJECT_PUBLISH     = "reject-publish"
+	OVERFLOW_REJECT_PUBLISH_DLX = "reject-publish-dlx"
+)
+
+// Headers exchanges don't route by the routing key, so deliveries couldn't be
+// matched to the subscriptions they were bound for
+var UnsupportedExchangeKind = errors.New("only topic, direct and fanout exchanges are supported")
+
+type exchange struct {
+	name string
+	kind string
+}
+
+// The amq.* exchanges are declared by the broker itself and can't be
+// redeclared, every other exchange is declared durable before it's used.
+func (e exchange) declare(channelInterface ChannelInterface) error {
+	if strings.HasPrefix(e.name, "amq.") {
+		return nil
+	}
+
+	return channelInterface.ExchangeDeclare(e.name, e.kind, true, false, false, false, nil)
+}
+
+func (e exchange) validate() error {
+	switch e.kind {
+	case amqp.ExchangeTopic, amqp.ExchangeDirect, amqp.ExchangeFanout:
+		return nil
+	}
+
+	return UnsupportedExchangeKind
+}
+
+// Whether the exchange routes a message with the routing key to a queue bound
+// with the binding key
+func (e exchange) matches(bindingKey, routingKey string) bool {
+	switch e.kind {
+	case amqp.ExchangeDirect:
+		return bindingKey == routingKey
+	case amqp.ExchangeFanout:
+		return true
+	default:
+		return platform.TopicMatches(bindingKey, routingKey)
+	}
+}
+
+func defaultExchange() exchange {
+	return exchange{
+		name: DEFAULT_EXCHANGE,
+		kind: amqp.ExchangeTopic,
+	}
+}
+
+// WithPublisherExchange publishes to the named exchange of the given kind,
+// declaring it if it doesn't exist yet. The kind has to be topic, direct or
+// fanout, NewPublisher returns UnsupportedExchangeKind otherwise.
+func WithPublisherExchange(name, kind string) PublisherOption {
+	return func(p *Publisher) {
+		p.exchange = exchange{
+			name: name,
+			kind: kind,
+		}
+	}
+}
+
+// WithSubscriberExchange binds the subscriber's queue to the named exchange of
+// the given kind, declaring it if it doesn't exist yet. Deliveries are matched
+// to subscriptions the way the exchange routes them: by topic wildcards, by the
+// exact routing key for a direct exchange, or all of them for a fanout one.
+// Any other kind makes the constructor return UnsupportedExchangeKind.
+func WithSubscriberExchange(name, kind string) SubscriberOption {
+	return func(s *Subscriber) {
+		s.exchange = exchange{
+			name: name,
+			kind: kind,
+		}
+	}
+}
+
+func withQueueArg(key string, value interface{}) SubscriberOption {
+	return func(s *Subscriber) {
+		if s.queueArgs == nil {
+			s.queueArgs = amqp.Table{}
+		}
+
+		s.queueArgs[key] = value
+	}
+}
+
+// WithMessageTTL discards messages that have been waiting in the queue for
+// longer than the ttl, or dead-letters them if the queue has a dead-letter
+// exchange.
+func WithMessageTTL(ttl time.Duration) SubscriberOption {
+	return withQueueArg("x-message-ttl", int64(ttl/time.Millisecond))
+}
+
+// WithMaxLength limits the number of ready messages in the queue, what happens
+// to the messages over the limit depends on the overflow policy.
+func WithMaxLength(maxLength int) SubscriberOption {
+	return withQueueArg("x-max-length", int64(maxLength))
+}
+
+// WithOverflow sets what the broker does once the queue reaches its max
+// length, one of the OVERFLOW_ policies.
+func WithOverflow(overflow string) SubscriberOption {
+	return withQueueArg("x-overflow", overflow)
+}
+
+// WithDeadLetterExchange republishes rejected, expired and dropped messages to
+// the named exchange.
+func WithDeadLetterExchange(exchange string) SubscriberOption {
+	return withQueueArg("x-dead-letter-exchange", exchange)
+}
+
+// WithLazyQueue keeps the queue's messages on disk rather than in memory.
+func WithLazyQueue() SubscriberOption {
+	return withQueueArg("x-queue-mode", "lazy")
+}
+
+// WithQuorumQueue declares a replicated quorum queue, which has to be durable
+// so it can't be used by exclusive or auto delete subscribers.
+func WithQuorumQueue() SubscriberOption {
+	return withQueueArg("x-queue-type", "quorum")
+}
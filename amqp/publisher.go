@@ -1,75 +1,269 @@
 package amqp
 
 import (
+	"errors"
 	"sync"
+	"time"
 
+	"github.com/Sirupsen/logrus"
 	"github.com/microplatform-io/platform"
 	"github.com/streadway/amqp"
 )
 
-const MAX_PUBLISH_RETRIES = 3
+const (
+	MAX_PUBLISH_RETRIES     = 3
+	DEFAULT_CONFIRM_TIMEOUT = 5 * time.Second
+)
+
+var (
+	PublishNacked          = errors.New("publish was nacked by the broker")
+	PublishConfirmTimeout  = errors.New("timed out waiting for the publish confirmation")
+	MandatoryNeedsConfirms = errors.New("mandatory publishes need confirms")
+	ConfirmChannelClosed   = errors.New("channel closed while waiting for the publish confirmation")
+)
+
+type PublisherOption func(*Publisher)
+
+// WithConfirms puts the publisher's channel into confirm mode, every publish
+// then waits for the broker to ack it. Nacked publishes are retried, while a
+// missing confirmation resets the channel before retrying.
+func WithConfirms(timeout time.Duration) PublisherOption {
+	return func(p *Publisher) {
+		p.confirms = true
+		p.confirmTimeout = timeout
+	}
+}
+
+// WithMandatory publishes with the mandatory flag, so that the broker returns
+// publishes that no queue is bound to and Publish fails with
+// platform.Unroutable. The broker sends the return ahead of the publish
+// confirmation, which is how a publish is known to have made it, so it has to
+// be combined with WithConfirms. NewPublisher returns MandatoryNeedsConfirms
+// otherwise.
+func WithMandatory() PublisherOption {
+	return func(p *Publisher) {
+		p.mandatory = true
+	}
+}
+
+// WithPersistentDelivery marks every publish as persistent, so that the ones
+// sitting in durable queues survive a restart of the broker. Combined with
+// WithConfirms, a confirmed publish is then one the broker has written to disk.
+func WithPersistentDelivery() PublisherOption {
+	return func(p *Publisher) {
+		p.persistent = true
+	}
+}
+
+// WithPublisherReconnectPolicy spaces out the publish retries and the attempts
+// to reconnect to the broker, DefaultReconnectPolicy otherwise.
+func WithPublisherReconnectPolicy(policy ReconnectPolicy) PublisherOption {
+	return func(p *Publisher) {
+		p.reconnectPolicy = policy
+		p.reconnector = newReconnector(policy)
+	}
+}
 
 type Publisher struct {
 	dialerInterface  DialerInterface
 	channelInterface ChannelInterface
+	tracker          *confirmTracker
 	mu               sync.Mutex
+
+	confirms       bool
+	confirmTimeout time.Duration
+	mandatory      bool
+	persistent     bool
+	exchange       exchange
+
+	reconnectPolicy ReconnectPolicy
+	reconnector     *reconnector
 }
 
-func (p *Publisher) getChannel() (ChannelInterface, error) {
-	if p.channelInterface != nil {
-		return p.channelInterface, nil
+func (p *Publisher) ConnectionState() ConnectionState {
+	return p.reconnector.ConnectionState()
+}
+
+func (p *Publisher) getChannel() (ChannelInterface, *confirmTracker, error) {
+	p.mu.Lock()
+	channelInterface, tracker := p.channelInterface, p.tracker
+	p.mu.Unlock()
+
+	if channelInterface != nil {
+		return channelInterface, tracker, nil
 	}
 
 	if err := p.resetChannel(); err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
-	return p.channelInterface, nil
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	return p.channelInterface, p.tracker, nil
 }
 
+// Reconnects unless the reconnect policy is still holding the reconnects back.
+// The channel it replaces is closed, which fails the publishes still waiting
+// for a confirmation on it.
 func (p *Publisher) resetChannel() error {
-	connection, err := p.dialerInterface.Dial()
-	if err != nil {
+	if err := p.reconnector.ready(); err != nil {
 		return err
 	}
 
-	channelInterface, err := connection.GetChannelInterface()
+	channelInterface, err := p.openChannel()
 	if err != nil {
+		p.reconnector.failed()
 		return err
 	}
 
+	p.reconnector.connected()
+
+	var tracker *confirmTracker
+
+	if p.confirms {
+		var returns chan amqp.Return
+		if p.mandatory {
+			returns = channelInterface.NotifyReturn(make(chan amqp.Return, 1))
+		}
+
+		tracker = newConfirmTracker(channelInterface.NotifyPublish(make(chan amqp.Confirmation, 1)), returns)
+	}
+
 	p.mu.Lock()
+	replaced := p.channelInterface
 	p.channelInterface = channelInterface
+	p.tracker = tracker
 	p.mu.Unlock()
 
+	if replaced != nil {
+		replaced.Close()
+	}
+
 	return nil
 }
 
+// Resets the channel a publish failed on, unless another publish has already
+// replaced it
+func (p *Publisher) replaceChannel(failed ChannelInterface) error {
+	p.mu.Lock()
+	current := p.channelInterface
+	p.mu.Unlock()
+
+	if current != failed {
+		return nil
+	}
+
+	return p.resetChannel()
+}
+
+func (p *Publisher) openChannel() (ChannelInterface, error) {
+	connection, err := p.dialerInterface.Dial()
+	if err != nil {
+		return nil, err
+	}
+
+	channelInterface, err := connection.GetChannelInterface()
+	if err != nil {
+		return nil, err
+	}
+
+	if err := p.exchange.declare(channelInterface); err != nil {
+		return nil, err
+	}
+
+	if p.confirms {
+		if err := channelInterface.Confirm(false); err != nil {
+			return nil, err
+		}
+	}
+
+	return channelInterface, nil
+}
+
+func waitForConfirmation(confirmed chan error, timeout time.Duration) error {
+	select {
+	case err := <-confirmed:
+		return err
+
+	case <-time.After(timeout):
+		return PublishConfirmTimeout
+	}
+}
+
+// Publish retries a failed publish a few times, but fails with
+// ReconnectPending right away rather than waiting out the reconnect policy
+// while it holds the reconnects back, so that callers are never blocked for
+// the length of an outage.
 func (p *Publisher) Publish(topic string, body []byte) error {
 	var publishErr error
 
+	retries := newBackoff(p.reconnectPolicy)
+
+	// Every attempt carries the same message id, so that consumers can tell a
+	// retried publish that did make it the first time apart from a new one
+	messageId, publishedAt := platform.CreateUUID(), time.Now()
+
 	for i := 0; i < MAX_PUBLISH_RETRIES; i++ {
-		channelInterface, err := p.getChannel()
+		if i > 0 {
+			interval, _ := retries.next()
+			time.Sleep(interval)
+		}
+
+		channelInterface, tracker, err := p.getChannel()
+		if err == ReconnectGaveUp || err == ReconnectPending {
+			return err
+		}
+
 		if err != nil {
 			publishErr = err
 			continue
 		}
 
-		publishErr = channelInterface.Publish(
-			"amq.topic", // exchange
-			topic,       // routing key
-			false,       // mandatory
-			false,       // immediate
-			amqp.Publishing{
-				ContentType: "text/plain",
-				Body:        body,
-			},
-		)
+		msg := amqp.Publishing{
+			ContentType: "text/plain",
+			MessageId:   messageId,
+			Timestamp:   publishedAt,
+			Body:        body,
+		}
+
+		if p.persistent {
+			msg.DeliveryMode = amqp.Persistent
+		}
+
+		if tracker != nil {
+			var confirmed chan error
+
+			confirmed, publishErr = tracker.publish(channelInterface, p.exchange.name, topic, p.mandatory, msg)
+			if publishErr == nil {
+				publishErr = waitForConfirmation(confirmed, p.confirmTimeout)
+			}
+		} else {
+			publishErr = channelInterface.Publish(
+				p.exchange.name, // exchange
+				topic,           // routing key
+				p.mandatory,     // mandatory
+				false,           // immediate
+				msg,
+			)
+		}
+
 		if publishErr == nil {
 			return nil
 		}
 
-		if err := p.resetChannel(); err != nil {
+		// Retrying won't help until something binds to the topic
+		if publishErr == platform.Unroutable {
+			return publishErr
+		}
+
+		// The channel is still healthy after a nack, the broker just couldn't take the message
+		if publishErr == PublishNacked {
+			logger.WithField("topic", topic).Warn("publish was nacked, retrying")
+			continue
+		}
+
+		if err := p.replaceChannel(channelInterface); err != nil {
 			continue
 		}
 	}
@@ -77,17 +271,191 @@ func (p *Publisher) Publish(topic string, body []byte) error {
 	return publishErr
 }
 
-func NewPublisher(dialerInterface DialerInterface) (*Publisher, error) {
-	return &Publisher{
+// Matches the confirmations of a channel in confirm mode up to the publishes
+// waiting for them by their delivery tag, so that publishes only wait for
+// their own confirmation rather than for each other. Returns carry no delivery
+// tag, they're matched by message id instead.
+type confirmTracker struct {
+	// Held while publishing, so that the delivery tags are handed out in the
+	// same order as the channel numbers the publishes
+	publishMu sync.Mutex
+	lastTag   uint64
+
+	mu      sync.Mutex
+	closed  bool
+	waiting map[uint64]*pendingConfirm
+}
+
+type pendingConfirm struct {
+	messageId string
+	confirmed chan error
+}
+
+// Publishes and registers the publish under the delivery tag the channel gives
+// it, which is the number of publishes made on the channel so far. Nothing is
+// held for the round trip to the broker. The publish is registered ahead of
+// time, since its confirmation may come back before Publish returns.
+func (t *confirmTracker) publish(channelInterface ChannelInterface, exchange, key string, mandatory bool, msg amqp.Publishing) (chan error, error) {
+	t.publishMu.Lock()
+	defer t.publishMu.Unlock()
+
+	tag := t.lastTag + 1
+	confirmed := make(chan error, 1)
+
+	t.mu.Lock()
+	if t.closed {
+		t.mu.Unlock()
+		return nil, ConfirmChannelClosed
+	}
+
+	t.waiting[tag] = &pendingConfirm{
+		messageId: msg.MessageId,
+		confirmed: confirmed,
+	}
+	t.mu.Unlock()
+
+	if err := channelInterface.Publish(exchange, key, mandatory, false, msg); err != nil {
+		t.mu.Lock()
+		delete(t.waiting, tag)
+		t.mu.Unlock()
+
+		return nil, err
+	}
+
+	t.lastTag = tag
+
+	return confirmed, nil
+}
+
+func (t *confirmTracker) run(confirmations chan amqp.Confirmation, returns chan amqp.Return) {
+	returned := map[string]bool{}
+
+	// The publish fails with platform.Unroutable, it's up to the caller whether
+	// that is worth more than a debug log
+	remember := func(r amqp.Return) {
+		logger.WithFields(logrus.Fields{
+			"topic":      r.RoutingKey,
+			"reply_code": r.ReplyCode,
+			"reply_text": r.ReplyText,
+			"exchange":   r.Exchange,
+		}).Debug("publish was returned by the broker")
+
+		returned[r.MessageId] = true
+	}
+
+	for {
+		select {
+		case r, ok := <-returns:
+			if !ok {
+				returns = nil
+				break
+			}
+
+			remember(r)
+
+		case confirmation, ok := <-confirmations:
+			if !ok {
+				t.close()
+				return
+			}
+
+			// Returns are sent ahead of the confirmation of the same publish, so
+			// any return for it is already waiting
+			for draining := returns != nil; draining; {
+				select {
+				case r, ok := <-returns:
+					if !ok {
+						returns = nil
+						draining = false
+						break
+					}
+
+					remember(r)
+
+				default:
+					draining = false
+				}
+			}
+
+			t.settle(confirmation, returned)
+		}
+	}
+}
+
+func (t *confirmTracker) settle(confirmation amqp.Confirmation, returned map[string]bool) {
+	t.mu.Lock()
+	pending, exists := t.waiting[confirmation.DeliveryTag]
+	delete(t.waiting, confirmation.DeliveryTag)
+	t.mu.Unlock()
+
+	if !exists {
+		return
+	}
+
+	switch {
+	case returned[pending.messageId]:
+		delete(returned, pending.messageId)
+		pending.confirmed <- platform.Unroutable
+
+	case !confirmation.Ack:
+		pending.confirmed <- PublishNacked
+
+	default:
+		pending.confirmed <- nil
+	}
+}
+
+// The channel is gone, nothing that is still waiting is going to be confirmed
+func (t *confirmTracker) close() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	t.closed = true
+
+	for tag, pending := range t.waiting {
+		pending.confirmed <- ConfirmChannelClosed
+		delete(t.waiting, tag)
+	}
+}
+
+func newConfirmTracker(confirmations chan amqp.Confirmation, returns chan amqp.Return) *confirmTracker {
+	t := &confirmTracker{
+		waiting: map[uint64]*pendingConfirm{},
+	}
+
+	go t.run(confirmations, returns)
+
+	return t
+}
+
+func NewPublisher(dialerInterface DialerInterface, options ...PublisherOption) (*Publisher, error) {
+	publisher := &Publisher{
 		dialerInterface: dialerInterface,
-	}, nil
+		exchange:        defaultExchange(),
+		reconnectPolicy: DefaultReconnectPolicy,
+		reconnector:     newReconnector(DefaultReconnectPolicy),
+	}
+
+	for _, option := range options {
+		option(publisher)
+	}
+
+	if err := publisher.exchange.validate(); err != nil {
+		return nil, err
+	}
+
+	if publisher.mandatory && !publisher.confirms {
+		return nil, MandatoryNeedsConfirms
+	}
+
+	return publisher, nil
 }
 
-func NewMultiPublisher(dialerInterfaces []DialerInterface) (platform.Publisher, error) {
+func NewMultiPublisher(dialerInterfaces []DialerInterface, options ...PublisherOption) (platform.Publisher, error) {
 	publishers := make([]platform.Publisher, len(dialerInterfaces))
 
 	for i := range dialerInterfaces {
-		publisher, err := NewPublisher(dialerInterfaces[i])
+		publisher, err := NewPublisher(dialerInterfaces[i], options...)
 		if err != nil {
 			return nil, err
 		}
@@ -1,10 +1,12 @@
 package amqp
 
 import (
+	"context"
 	"errors"
 	"testing"
 	"time"
 
+	"github.com/microplatform-io/platform"
 	. "github.com/smartystreets/goconvey/convey"
 	"github.com/streadway/amqp"
 )
@@ -17,11 +19,45 @@ func TestNewPublisher(t *testing.T) {
 		So(publisher, ShouldNotBeNil)
 		So(err, ShouldBeNil)
 
-		So(mockDialer.totalDials, ShouldEqual, 0)
+		So(mockDialer.getTotalDials(), ShouldEqual, 0)
 	})
 }
 
 func TestPublisherPublish(t *testing.T) {
+	Convey("Publishing with a custom exchange should declare it and publish to it", t, func() {
+		mockDialer := newMockDialer()
+
+		publisher, err := NewPublisher(mockDialer, WithPublisherExchange("testing-exchange", amqp.ExchangeTopic))
+		So(publisher, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
+
+		So(mockDialer.getConnection().channel.mockExchangeDeclares, ShouldResemble, []mockExchangeDeclare{
+			mockExchangeDeclare{
+				name:    "testing-exchange",
+				kind:    "topic",
+				durable: true,
+			},
+		})
+		So(withoutMessageIds(mockDialer.getConnection().channel.mockPublishes), ShouldResemble, []mockPublish{
+			mockPublish{
+				exchange: "testing-exchange",
+				key:      "testing",
+				msg: amqp.Publishing{
+					ContentType: "text/plain",
+					Body:        []byte{},
+				},
+			},
+		})
+	})
+
+	Convey("Creating a publisher for a headers exchange should fail", t, func() {
+		publisher, err := NewPublisher(newMockDialer(), WithPublisherExchange("testing-exchange", amqp.ExchangeHeaders))
+		So(publisher, ShouldBeNil)
+		So(err, ShouldEqual, UnsupportedExchangeKind)
+	})
+
 	Convey("Ensure that publishing records properly", t, func() {
 		mockDialer := newMockDialer()
 
@@ -29,12 +65,12 @@ func TestPublisherPublish(t *testing.T) {
 		So(publisher, ShouldNotBeNil)
 		So(err, ShouldBeNil)
 
-		So(mockDialer.totalDials, ShouldEqual, 0)
+		So(mockDialer.getTotalDials(), ShouldEqual, 0)
 
 		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
 
-		So(mockDialer.totalDials, ShouldEqual, 1)
-		So(mockDialer.connection.channel.mockPublishes, ShouldResemble, []mockPublish{
+		So(mockDialer.getTotalDials(), ShouldEqual, 1)
+		So(withoutMessageIds(mockDialer.getConnection().channel.mockPublishes), ShouldResemble, []mockPublish{
 			mockPublish{
 				exchange: "amq.topic",
 				key:      "testing",
@@ -53,12 +89,12 @@ func TestPublisherPublish(t *testing.T) {
 		So(publisher, ShouldNotBeNil)
 		So(err, ShouldBeNil)
 
-		So(mockDialer.totalDials, ShouldEqual, 0)
+		So(mockDialer.getTotalDials(), ShouldEqual, 0)
 
 		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
 
-		So(mockDialer.totalDials, ShouldEqual, 1)
-		So(mockDialer.connection.channel.mockPublishes, ShouldResemble, []mockPublish{
+		So(mockDialer.getTotalDials(), ShouldEqual, 1)
+		So(withoutMessageIds(mockDialer.getConnection().channel.mockPublishes), ShouldResemble, []mockPublish{
 			mockPublish{
 				exchange: "amq.topic",
 				key:      "testing",
@@ -69,18 +105,18 @@ func TestPublisherPublish(t *testing.T) {
 			},
 		})
 
-		firstConnection := mockDialer.connection
+		firstConnection := mockDialer.getConnection()
 
-		closeErr := mockDialer.connection.Close()
+		closeErr := mockDialer.getConnection().Close()
 		So(closeErr, ShouldBeNil)
 
 		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
 
 		// Ensure that a new connection was generated
-		So(firstConnection, ShouldNotEqual, mockDialer.connection)
+		So(firstConnection, ShouldNotEqual, mockDialer.getConnection())
 
-		So(mockDialer.totalDials, ShouldEqual, 2)
-		So(firstConnection.channel.mockPublishes, ShouldResemble, []mockPublish{
+		So(mockDialer.getTotalDials(), ShouldEqual, 2)
+		So(withoutMessageIds(firstConnection.channel.mockPublishes), ShouldResemble, []mockPublish{
 			mockPublish{
 				exchange: "amq.topic",
 				key:      "testing",
@@ -90,7 +126,7 @@ func TestPublisherPublish(t *testing.T) {
 				},
 			},
 		})
-		So(mockDialer.connection.channel.mockPublishes, ShouldResemble, []mockPublish{
+		So(withoutMessageIds(mockDialer.getConnection().channel.mockPublishes), ShouldResemble, []mockPublish{
 			mockPublish{
 				exchange: "amq.topic",
 				key:      "testing",
@@ -109,17 +145,17 @@ func TestPublisherPublish(t *testing.T) {
 		So(publisher, ShouldNotBeNil)
 		So(err, ShouldBeNil)
 
-		So(mockDialer.totalDials, ShouldEqual, 0)
+		So(mockDialer.getTotalDials(), ShouldEqual, 0)
 
 		go func() {
-			<-mockDialer.connected
-			mockDialer.connection.channel.errorOnPublish(errors.New("failed to publish..."))
+			<-mockDialer.getConnected()
+			mockDialer.getConnection().channel.errorOnPublish(errors.New("failed to publish..."))
 		}()
 
 		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
 
-		So(mockDialer.totalDials, ShouldEqual, 2)
-		So(mockDialer.connection.channel.mockPublishes, ShouldResemble, []mockPublish{
+		So(mockDialer.getTotalDials(), ShouldEqual, 2)
+		So(withoutMessageIds(mockDialer.getConnection().channel.mockPublishes), ShouldResemble, []mockPublish{
 			mockPublish{
 				exchange: "amq.topic",
 				key:      "testing",
@@ -138,30 +174,30 @@ func TestPublisherPublish(t *testing.T) {
 		So(publisher, ShouldNotBeNil)
 		So(err, ShouldBeNil)
 
-		So(mockDialer.totalDials, ShouldEqual, 0)
+		So(mockDialer.getTotalDials(), ShouldEqual, 0)
 
 		go func() {
-			<-mockDialer.connected
-			mockDialer.connection.Close()
+			<-mockDialer.getConnected()
+			mockDialer.getConnection().Close()
 		}()
 
 		time.Sleep(10 * time.Millisecond)
 
 		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
 
-		So(mockDialer.totalDials, ShouldEqual, 1)
+		So(mockDialer.getTotalDials(), ShouldEqual, 1)
 
 		go func() {
-			<-mockDialer.connected
-			mockDialer.connection.channel.errorOnPublish(errors.New("failed to publish..."))
+			<-mockDialer.getConnected()
+			mockDialer.getConnection().channel.errorOnPublish(errors.New("failed to publish..."))
 		}()
 
 		time.Sleep(10 * time.Millisecond)
 
 		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
 
-		So(mockDialer.totalDials, ShouldEqual, 3)
-		So(mockDialer.connection.channel.mockPublishes, ShouldResemble, []mockPublish{
+		So(mockDialer.getTotalDials(), ShouldEqual, 3)
+		So(withoutMessageIds(mockDialer.getConnection().channel.mockPublishes), ShouldResemble, []mockPublish{
 			mockPublish{
 				exchange: "amq.topic",
 				key:      "testing",
@@ -180,19 +216,19 @@ func TestPublisherPublish(t *testing.T) {
 		So(publisher, ShouldNotBeNil)
 		So(err, ShouldBeNil)
 
-		So(mockDialer.totalDials, ShouldEqual, 0)
+		So(mockDialer.getTotalDials(), ShouldEqual, 0)
 
 		go func() {
-			<-mockDialer.connected
-			mockDialer.connection.channel.errorOnPublish(errors.New("failed to publish 1..."))
+			<-mockDialer.getConnected()
+			mockDialer.getConnection().channel.errorOnPublish(errors.New("failed to publish 1..."))
 
 			go func() {
-				<-mockDialer.connected
-				mockDialer.connection.channel.errorOnPublish(errors.New("failed to publish 2..."))
+				<-mockDialer.getConnected()
+				mockDialer.getConnection().channel.errorOnPublish(errors.New("failed to publish 2..."))
 
 				go func() {
-					<-mockDialer.connected
-					mockDialer.connection.channel.errorOnPublish(errors.New("failed to publish 3..."))
+					<-mockDialer.getConnected()
+					mockDialer.getConnection().channel.errorOnPublish(errors.New("failed to publish 3..."))
 				}()
 			}()
 		}()
@@ -202,7 +238,218 @@ func TestPublisherPublish(t *testing.T) {
 		So(publisher.Publish("testing", []byte{}), ShouldResemble, errors.New("failed to publish 3..."))
 
 		// The first connect plus every failure triggering a reconnect
-		So(mockDialer.totalDials, ShouldEqual, 4)
-		So(mockDialer.connection.channel.mockPublishes, ShouldResemble, []mockPublish{})
+		So(mockDialer.getTotalDials(), ShouldEqual, 4)
+		So(mockDialer.getConnection().channel.mockPublishes, ShouldResemble, []mockPublish{})
+	})
+}
+
+func TestPublisherConfirms(t *testing.T) {
+	Convey("Publishing with confirms should put the channel in confirm mode and wait for the ack", t, func() {
+		mockDialer := newMockDialer()
+
+		publisher, err := NewPublisher(mockDialer, WithConfirms(100*time.Millisecond))
+		So(publisher, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
+		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
+
+		So(mockDialer.getTotalDials(), ShouldEqual, 1)
+		So(mockDialer.getConnection().channel.confirmMode, ShouldBeTrue)
+		So(len(mockDialer.getConnection().channel.mockPublishes), ShouldEqual, 2)
+	})
+
+	Convey("Publishing with persistent delivery should mark every publish as persistent", t, func() {
+		mockDialer := newMockDialer()
+
+		publisher, err := NewPublisher(mockDialer, WithConfirms(100*time.Millisecond), WithPersistentDelivery())
+		So(err, ShouldBeNil)
+
+		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
+		So(mockDialer.getConnection().channel.mockPublishes[0].msg.DeliveryMode, ShouldEqual, amqp.Persistent)
+	})
+
+	Convey("A nacked publish should be retried on the same channel", t, func() {
+		mockDialer := newMockDialer()
+
+		publisher, err := NewPublisher(mockDialer, WithConfirms(100*time.Millisecond))
+		So(publisher, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		go func() {
+			<-mockDialer.getConnected()
+			mockDialer.getConnection().channel.nackOnPublish()
+		}()
+
+		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
+
+		So(mockDialer.getTotalDials(), ShouldEqual, 1)
+		So(len(mockDialer.getConnection().channel.mockPublishes), ShouldEqual, 2)
+	})
+
+	Convey("A publish that is never confirmed should reset the channel and be retried", t, func() {
+		mockDialer := newMockDialer()
+
+		publisher, err := NewPublisher(mockDialer, WithConfirms(50*time.Millisecond))
+		So(publisher, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		go func() {
+			<-mockDialer.getConnected()
+			mockDialer.getConnection().channel.dropConfirmOnPublish()
+		}()
+
+		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
+
+		So(mockDialer.getTotalDials(), ShouldEqual, 2)
+		So(len(mockDialer.getConnection().channel.mockPublishes), ShouldEqual, 1)
+	})
+
+	Convey("A publish should only wait for its own confirmation", t, func() {
+		mockDialer := newMockDialer()
+
+		publisher, err := NewPublisher(mockDialer, WithConfirms(200*time.Millisecond))
+		So(publisher, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		So(publisher.resetChannel(), ShouldBeNil)
+
+		firstChannel := mockDialer.getConnection().channel
+		firstChannel.dropConfirmOnPublish()
+
+		unconfirmed := make(chan error)
+		go func() {
+			unconfirmed <- publisher.Publish("unconfirmed", []byte{})
+		}()
+
+		time.Sleep(20 * time.Millisecond)
+
+		startedAt := time.Now()
+		So(publisher.Publish("confirmed", []byte{}), ShouldBeNil)
+		So(time.Since(startedAt), ShouldBeLessThan, 100*time.Millisecond)
+
+		// Retried on a new channel once the confirmation timed out, the old one is closed
+		So(<-unconfirmed, ShouldBeNil)
+		So(firstChannel.isClosed(), ShouldBeTrue)
+		So(mockDialer.getTotalDials(), ShouldEqual, 2)
+	})
+
+	Convey("A publish that keeps getting nacked should fail", t, func() {
+		mockDialer := newMockDialer()
+
+		publisher, err := NewPublisher(mockDialer, WithConfirms(100*time.Millisecond))
+		So(publisher, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		go func() {
+			<-mockDialer.getConnected()
+			mockDialer.getConnection().channel.nackOnPublish().nackOnPublish().nackOnPublish()
+		}()
+
+		So(publisher.Publish("testing", []byte{}), ShouldEqual, PublishNacked)
+		So(len(mockDialer.getConnection().channel.mockPublishes), ShouldEqual, MAX_PUBLISH_RETRIES)
+	})
+}
+
+func TestPublisherMandatory(t *testing.T) {
+	Convey("A mandatory publisher without confirms should fail to be created", t, func() {
+		publisher, err := NewPublisher(newMockDialer(), WithMandatory())
+		So(publisher, ShouldBeNil)
+		So(err, ShouldEqual, MandatoryNeedsConfirms)
+	})
+
+	Convey("A mandatory publish that is routed should succeed", t, func() {
+		mockDialer := newMockDialer()
+
+		publisher, err := NewPublisher(mockDialer, WithConfirms(DEFAULT_CONFIRM_TIMEOUT), WithMandatory())
+		So(publisher, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
+
+		So(mockDialer.getConnection().channel.confirmMode, ShouldBeTrue)
+		So(mockDialer.getConnection().channel.mockPublishes[0].mandatory, ShouldBeTrue)
+	})
+
+	Convey("A mandatory publish that is returned should fail as unroutable without retrying", t, func() {
+		mockDialer := newMockDialer()
+
+		publisher, err := NewPublisher(mockDialer, WithConfirms(DEFAULT_CONFIRM_TIMEOUT), WithMandatory())
+		So(publisher, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		go func() {
+			<-mockDialer.getConnected()
+			mockDialer.getConnection().channel.returnOnPublish("nobody-home")
+		}()
+
+		So(publisher.Publish("nobody-home", []byte{}), ShouldEqual, platform.Unroutable)
+
+		So(mockDialer.getTotalDials(), ShouldEqual, 1)
+		So(len(mockDialer.getConnection().channel.mockPublishes), ShouldEqual, 1)
+
+		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
+	})
+}
+
+func TestPublisherMessageIds(t *testing.T) {
+	Convey("Every publish should get its own message id and a timestamp", t, func() {
+		mockDialer := newMockDialer()
+
+		publisher, err := NewPublisher(mockDialer)
+		So(publisher, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
+		So(publisher.Publish("testing", []byte{}), ShouldBeNil)
+
+		mockPublishes := mockDialer.getConnection().channel.mockPublishes
+		So(len(mockPublishes), ShouldEqual, 2)
+
+		So(mockPublishes[0].msg.MessageId, ShouldNotBeEmpty)
+		So(mockPublishes[0].msg.Timestamp.IsZero(), ShouldBeFalse)
+		So(mockPublishes[1].msg.MessageId, ShouldNotEqual, mockPublishes[0].msg.MessageId)
+	})
+}
+
+// Message ids and timestamps differ on every publish, they're left out of the
+// comparisons of what was published
+func withoutMessageIds(publishes []mockPublish) []mockPublish {
+	stripped := make([]mockPublish, len(publishes))
+
+	for i, publish := range publishes {
+		publish.msg.MessageId = ""
+		publish.msg.Timestamp = time.Time{}
+		stripped[i] = publish
+	}
+
+	return stripped
+}
+
+func TestPublisherMandatoryRouting(t *testing.T) {
+	Convey("A request nobody is bound to should come back from the router as unavailable", t, func() {
+		publisherDialer := newMockDialer()
+
+		publisher, err := NewPublisher(publisherDialer, WithConfirms(DEFAULT_CONFIRM_TIMEOUT), WithMandatory())
+		So(err, ShouldBeNil)
+
+		So(publisher.resetChannel(), ShouldBeNil)
+		publisherDialer.getConnection().channel.returnOnPublish(platform.RoutingKey("microservice", "/testing/get/nobody"))
+
+		subscriber, err := NewSubscriber(newMockDialer(), "testing-router")
+		So(err, ShouldBeNil)
+		defer subscriber.Close(context.Background())
+
+		router := platform.NewStandardRouterWithTopic(publisher, subscriber, "testing-router")
+
+		response, err := router.RouteContext(context.Background(), &platform.Request{
+			Uuid:    platform.String(platform.CreateUUID()),
+			Routing: platform.RouteToUri("microservice:///testing/get/nobody"),
+		})
+
+		platformError, ok := err.(*platform.PlatformError)
+		So(ok, ShouldBeTrue)
+		So(platformError.Code, ShouldEqual, platform.Error_UNAVAILABLE)
+		So(platform.IsErrorResponse(response), ShouldBeTrue)
 	})
 }
@@ -0,0 +1,207 @@
+package amqp
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"sync"
+	"time"
+
+	"github.com/microplatform-io/platform"
+)
+
+var (
+	ReconnectPending = errors.New("waiting before reconnecting to the broker")
+	ReconnectGaveUp  = errors.New("gave up reconnecting to the broker")
+)
+
+// ReconnectPolicy spaces out the attempts to reach the broker while it's
+// unavailable, so that an outage doesn't flood the logs and the broker.
+type ReconnectPolicy struct {
+	// The wait after the first failure, multiplied after every failure that follows
+	InitialInterval time.Duration
+	Multiplier      float64
+
+	// Caps the wait between two attempts
+	MaxInterval time.Duration
+
+	// Randomizes every wait by up to this fraction either way, so that every
+	// client of a broker that went down doesn't come back at the same time
+	Jitter float64
+
+	// Gives up once the broker has been unavailable for this long, never when zero
+	MaxElapsedTime time.Duration
+}
+
+var DefaultReconnectPolicy = ReconnectPolicy{
+	InitialInterval: 100 * time.Millisecond,
+	Multiplier:      2,
+	MaxInterval:     30 * time.Second,
+	Jitter:          0.2,
+}
+
+// Tracks the waits of a single outage
+type backoff struct {
+	policy  ReconnectPolicy
+	attempt int
+	started time.Time
+}
+
+// The wait before the next attempt, false once the policy gave up
+func (b *backoff) next() (time.Duration, bool) {
+	now := time.Now()
+
+	if b.attempt == 0 {
+		b.started = now
+	}
+
+	if b.policy.MaxElapsedTime > 0 && now.Sub(b.started) >= b.policy.MaxElapsedTime {
+		return 0, false
+	}
+
+	interval := float64(b.policy.InitialInterval)
+	for i := 0; i < b.attempt && (b.policy.MaxInterval <= 0 || interval < float64(b.policy.MaxInterval)); i++ {
+		interval *= b.policy.Multiplier
+	}
+
+	if b.policy.MaxInterval > 0 && interval > float64(b.policy.MaxInterval) {
+		interval = float64(b.policy.MaxInterval)
+	}
+
+	interval += interval * b.policy.Jitter * (2*rand.Float64() - 1)
+
+	b.attempt++
+
+	return time.Duration(interval), true
+}
+
+func (b *backoff) reset() {
+	b.attempt = 0
+}
+
+func newBackoff(policy ReconnectPolicy) *backoff {
+	return &backoff{
+		policy: policy,
+	}
+}
+
+type ConnectionState int
+
+const (
+	// Not connected yet, or closed
+	Disconnected ConnectionState = iota
+	Connected
+	Reconnecting
+	GaveUp
+)
+
+func (s ConnectionState) String() string {
+	switch s {
+	case Disconnected:
+		return "disconnected"
+	case Connected:
+		return "connected"
+	case Reconnecting:
+		return "reconnecting"
+	case GaveUp:
+		return "gave up reconnecting"
+	}
+
+	return "unknown"
+}
+
+type ConnectionStateReporter interface {
+	ConnectionState() ConnectionState
+}
+
+// NewConnectionHealthChecker reports the publisher, subscriber or dialer as
+// unhealthy while it's reconnecting or once it gave up.
+func NewConnectionHealthChecker(name string, reporter ConnectionStateReporter) platform.HealthChecker {
+	return platform.HealthCheckerFunc(func() error {
+		switch state := reporter.ConnectionState(); state {
+		case Reconnecting, GaveUp:
+			return fmt.Errorf("%s is %s", name, state)
+		}
+
+		return nil
+	})
+}
+
+// Keeps track of the connection state and holds the attempts to reconnect
+// back following the reconnect policy.
+type reconnector struct {
+	mu          sync.Mutex
+	backoff     *backoff
+	state       ConnectionState
+	nextAttempt time.Time
+}
+
+func (r *reconnector) ConnectionState() ConnectionState {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.state
+}
+
+func (r *reconnector) connected() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.backoff.reset()
+	r.state = Connected
+	r.nextAttempt = time.Time{}
+}
+
+// The connection went away, the next attempt doesn't have to wait
+func (r *reconnector) lost() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.state = Reconnecting
+}
+
+func (r *reconnector) disconnected() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.state = Disconnected
+}
+
+// Records a failed attempt and returns how long to wait before the next one
+func (r *reconnector) failed() (time.Duration, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	interval, ok := r.backoff.next()
+	if !ok {
+		r.state = GaveUp
+		return 0, ReconnectGaveUp
+	}
+
+	r.state = Reconnecting
+	r.nextAttempt = time.Now().Add(interval)
+
+	return interval, nil
+}
+
+// Whether an attempt can be made right now
+func (r *reconnector) ready() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.state == GaveUp {
+		return ReconnectGaveUp
+	}
+
+	if time.Now().Before(r.nextAttempt) {
+		return ReconnectPending
+	}
+
+	return nil
+}
+
+func newReconnector(policy ReconnectPolicy) *reconnector {
+	return &reconnector{
+		backoff: newBackoff(policy),
+	}
+}
@@ -0,0 +1,208 @@
+package amqp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestBackoff(t *testing.T) {
+	Convey("The wait should grow with every attempt up to the max interval", t, func() {
+		b := newBackoff(ReconnectPolicy{
+			InitialInterval: 100 * time.Millisecond,
+			Multiplier:      2,
+			MaxInterval:     300 * time.Millisecond,
+		})
+
+		for _, expected := range []time.Duration{100 * time.Millisecond, 200 * time.Millisecond, 300 * time.Millisecond, 300 * time.Millisecond} {
+			interval, ok := b.next()
+			So(ok, ShouldBeTrue)
+			So(interval, ShouldEqual, expected)
+		}
+
+		b.reset()
+
+		interval, _ := b.next()
+		So(interval, ShouldEqual, 100*time.Millisecond)
+	})
+
+	Convey("The jitter should keep the wait within its fraction of the interval", t, func() {
+		b := newBackoff(ReconnectPolicy{
+			InitialInterval: 100 * time.Millisecond,
+			Multiplier:      2,
+			Jitter:          0.5,
+		})
+
+		for i := 0; i < 100; i++ {
+			b.reset()
+
+			interval, _ := b.next()
+			So(interval, ShouldBeBetweenOrEqual, 50*time.Millisecond, 150*time.Millisecond)
+		}
+	})
+
+	Convey("The backoff should give up once the max elapsed time is up", t, func() {
+		b := newBackoff(ReconnectPolicy{
+			InitialInterval: time.Millisecond,
+			Multiplier:      1,
+			MaxElapsedTime:  20 * time.Millisecond,
+		})
+
+		_, ok := b.next()
+		So(ok, ShouldBeTrue)
+
+		time.Sleep(30 * time.Millisecond)
+
+		_, ok = b.next()
+		So(ok, ShouldBeFalse)
+	})
+}
+
+func TestReconnector(t *testing.T) {
+	Convey("A failure should hold the next attempt back until its wait is over", t, func() {
+		r := newReconnector(ReconnectPolicy{
+			InitialInterval: 20 * time.Millisecond,
+			Multiplier:      2,
+		})
+
+		So(r.ready(), ShouldBeNil)
+		So(r.ConnectionState(), ShouldEqual, Disconnected)
+
+		_, err := r.failed()
+		So(err, ShouldBeNil)
+		So(r.ConnectionState(), ShouldEqual, Reconnecting)
+		So(r.ready(), ShouldEqual, ReconnectPending)
+
+		time.Sleep(30 * time.Millisecond)
+		So(r.ready(), ShouldBeNil)
+
+		r.connected()
+		So(r.ConnectionState(), ShouldEqual, Connected)
+	})
+
+	Convey("A reconnector that gave up should stay that way", t, func() {
+		r := newReconnector(ReconnectPolicy{
+			InitialInterval: time.Millisecond,
+			Multiplier:      1,
+			MaxElapsedTime:  time.Nanosecond,
+		})
+
+		r.failed()
+		time.Sleep(time.Millisecond)
+
+		_, err := r.failed()
+		So(err, ShouldEqual, ReconnectGaveUp)
+		So(r.ConnectionState(), ShouldEqual, GaveUp)
+		So(r.ready(), ShouldEqual, ReconnectGaveUp)
+	})
+}
+
+func TestConnectionHealthChecker(t *testing.T) {
+	Convey("The health checker should only complain while reconnecting or once given up", t, func() {
+		r := newReconnector(DefaultReconnectPolicy)
+		healthChecker := NewConnectionHealthChecker("testing", r)
+
+		So(healthChecker.CheckHealth(), ShouldBeNil)
+
+		r.failed()
+		So(healthChecker.CheckHealth(), ShouldResemble, errors.New("testing is reconnecting"))
+
+		r.connected()
+		So(healthChecker.CheckHealth(), ShouldBeNil)
+	})
+}
+
+func TestPublisherReconnect(t *testing.T) {
+	Convey("A publisher that failed to dial should hold its next dial back", t, func() {
+		mockDialer := newMockDialer()
+		mockDialer.dialErr = errors.New("testing")
+
+		publisher, err := NewPublisher(mockDialer, WithPublisherReconnectPolicy(ReconnectPolicy{
+			InitialInterval: time.Second,
+			Multiplier:      2,
+		}))
+		So(err, ShouldBeNil)
+
+		So(publisher.resetChannel(), ShouldEqual, mockDialer.dialErr)
+		So(publisher.ConnectionState(), ShouldEqual, Reconnecting)
+
+		So(publisher.resetChannel(), ShouldEqual, ReconnectPending)
+		So(mockDialer.getTotalDials(), ShouldEqual, 1)
+	})
+
+	Convey("Publish retries should be spaced out following the reconnect policy", t, func() {
+		mockDialer := newMockDialer()
+		mockDialer.dialErr = errors.New("testing")
+
+		publisher, err := NewPublisher(mockDialer, WithPublisherReconnectPolicy(ReconnectPolicy{
+			InitialInterval: 20 * time.Millisecond,
+			Multiplier:      2,
+		}))
+		So(err, ShouldBeNil)
+
+		started := time.Now()
+
+		So(publisher.Publish("testing", []byte{}), ShouldEqual, mockDialer.dialErr)
+
+		// 20ms before the second attempt and 40ms before the third
+		So(time.Since(started), ShouldBeGreaterThanOrEqualTo, 60*time.Millisecond)
+		So(mockDialer.getTotalDials(), ShouldEqual, 3)
+	})
+
+	Convey("Publishing should fail right away while a reconnect is pending", t, func() {
+		mockDialer := newMockDialer()
+
+		publisher, err := NewPublisher(mockDialer, WithPublisherReconnectPolicy(ReconnectPolicy{
+			InitialInterval: time.Minute,
+			Multiplier:      1,
+		}))
+		So(err, ShouldBeNil)
+
+		publisher.reconnector.failed()
+
+		started := time.Now()
+
+		So(publisher.Publish("testing", []byte{}), ShouldEqual, ReconnectPending)
+		So(time.Since(started), ShouldBeLessThan, time.Second)
+		So(mockDialer.getTotalDials(), ShouldEqual, 0)
+	})
+
+	Convey("Publishing should fail right away once the reconnect policy gave up", t, func() {
+		mockDialer := newMockDialer()
+
+		publisher, err := NewPublisher(mockDialer, WithPublisherReconnectPolicy(ReconnectPolicy{
+			InitialInterval: time.Second,
+			MaxElapsedTime:  time.Nanosecond,
+		}))
+		So(err, ShouldBeNil)
+
+		publisher.reconnector.failed()
+		time.Sleep(time.Millisecond)
+		publisher.reconnector.failed()
+
+		started := time.Now()
+
+		So(publisher.Publish("testing", []byte{}), ShouldEqual, ReconnectGaveUp)
+		So(time.Since(started), ShouldBeLessThan, time.Second)
+		So(mockDialer.getTotalDials(), ShouldEqual, 0)
+	})
+}
+
+func TestSubscriberReconnect(t *testing.T) {
+	Convey("Starting a subscriber should fail once the reconnect policy gave up", t, func() {
+		mockDialer := newMockDialer()
+		mockDialer.dialErr = errors.New("testing")
+
+		subscriber, err := NewSubscriber(mockDialer, "testing-queue", WithSubscriberReconnectPolicy(ReconnectPolicy{
+			InitialInterval: time.Millisecond,
+			Multiplier:      1,
+			MaxElapsedTime:  20 * time.Millisecond,
+		}))
+		So(err, ShouldBeNil)
+
+		So(subscriber.Start(), ShouldEqual, ReconnectGaveUp)
+		So(subscriber.ConnectionState(), ShouldEqual, GaveUp)
+	})
+}
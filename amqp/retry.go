@@ -0,0 +1,222 @@
+package amqp
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+	"github.com/streadway/amqp"
+)
+
+// Headers carried by messages that failed to be handled
+const (
+	ATTEMPT_HEADER              = "x-attempt"
+	ERROR_HEADER                = "x-error"
+	ORIGINAL_ROUTING_KEY_HEADER = "x-original-routing-key"
+	SUBSCRIPTION_TOPIC_HEADER   = "x-subscription-topic"
+)
+
+var (
+	RetriesNeedNamedQueue  = errors.New("Retries need a named queue that isn't exclusive")
+	RetriesNeedAtLeastOnce = errors.New("Retries need the at-least-once delivery mode")
+)
+
+// RetryPolicy decides what happens to a message once a handler returns an
+// error for it. The message waits in a retry queue until its delay is up and
+// is then dead-lettered back into the subscriber's queue, until it has been
+// attempted MaxAttempts times and is parked in the <queue>.dead queue.
+type RetryPolicy struct {
+	// The total number of times a message is handled, including the first
+	MaxAttempts int
+
+	// The delay before the second attempt, doubling with every attempt after
+	Delay time.Duration
+
+	// Caps the delay between attempts, no cap when zero
+	MaxDelay time.Duration
+}
+
+// The delay after the given attempt failed
+func (p RetryPolicy) delay(attempt int) time.Duration {
+	delay := p.Delay
+
+	for i := 1; i < attempt && (p.MaxDelay <= 0 || delay < p.MaxDelay); i++ {
+		delay *= 2
+	}
+
+	if p.MaxDelay > 0 && delay > p.MaxDelay {
+		return p.MaxDelay
+	}
+
+	return delay
+}
+
+// WithRetryPolicy retries the messages whose handler returned an error
+// following the policy, instead of dropping them. The subscriber has to be in
+// at-least-once mode, so that a delivery is only acked once the broker
+// confirmed its retry and is requeued if that failed, and its queue has to be
+// named and not exclusive for the retries to find their way back. The
+// constructors return RetriesNeedAtLeastOnce or RetriesNeedNamedQueue
+// otherwise.
+func WithRetryPolicy(policy RetryPolicy) SubscriberOption {
+	return func(s *Subscriber) {
+		s.retryPolicy = &policy
+	}
+}
+
+func retryQueueName(queue string, attempt int) string {
+	return fmt.Sprintf("%s.retry.%d", queue, attempt)
+}
+
+func deadQueueName(queue string) string {
+	return queue + ".dead"
+}
+
+// Messages start at their first attempt, every retry records the attempt it's on
+func deliveryAttempt(msg DeliveryInterface) int {
+	switch attempt := msg.GetHeaders()[ATTEMPT_HEADER].(type) {
+	case int32:
+		return int(attempt)
+	case int64:
+		return int(attempt)
+	case int:
+		return attempt
+	}
+
+	return 1
+}
+
+// Retried messages come back through the default exchange, routed by the name
+// of the queue, so the routing key they were originally published with is
+// kept in a header.
+func deliveryRoutingKey(msg DeliveryInterface) string {
+	if routingKey, ok := msg.GetHeaders()[ORIGINAL_ROUTING_KEY_HEADER].(string); ok {
+		return routingKey
+	}
+
+	return msg.GetRoutingKey()
+}
+
+func (s *Subscriber) validateRetries() error {
+	if s.retryPolicy == nil {
+		return nil
+	}
+
+	if s.queue == "" || s.exclusive {
+		return RetriesNeedNamedQueue
+	}
+
+	if !s.atLeastOnce {
+		return RetriesNeedAtLeastOnce
+	}
+
+	return nil
+}
+
+// Every retry attempt gets its own queue whose message ttl is the delay for
+// that attempt, expired messages are dead-lettered through the default
+// exchange straight back into the subscriber's queue.
+func (s *Subscriber) declareRetryQueues(channelInterface ChannelInterface) error {
+	if err := s.validateRetries(); err != nil {
+		return err
+	}
+
+	for attempt := 1; attempt < s.retryPolicy.MaxAttempts; attempt++ {
+		args := amqp.Table{
+			"x-message-ttl":             int64(s.retryPolicy.delay(attempt) / time.Millisecond),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": s.queue,
+		}
+
+		if _, err := channelInterface.QueueDeclare(retryQueueName(s.queue, attempt), true, false, false, false, args); err != nil {
+			return err
+		}
+	}
+
+	_, err := channelInterface.QueueDeclare(deadQueueName(s.queue), true, false, false, false, nil)
+
+	return err
+}
+
+// Puts the consumer's channel in confirm mode, so that a failed message is
+// only acked once the broker confirmed its retry.
+func (s *Subscriber) confirmRetries(channelInterface ChannelInterface) error {
+	if err := channelInterface.Confirm(false); err != nil {
+		return err
+	}
+
+	tracker := newConfirmTracker(channelInterface.NotifyPublish(make(chan amqp.Confirmation, 1)), nil)
+
+	s.mu.Lock()
+	s.retryTracker = tracker
+	s.mu.Unlock()
+
+	return nil
+}
+
+// Publishes the failed message to the retry queue for its attempt, or parks it
+// in the dead queue once it ran out of attempts. The retried message only goes
+// back to the subscription that failed to handle it. Returns whether the
+// broker confirmed the message has been taken care of, otherwise it's up to
+// the delivery mode.
+func (s *Subscriber) retry(topic string, msg DeliveryInterface, handleErr error) bool {
+	attempt := deliveryAttempt(msg)
+
+	entry := logger.WithFields(logrus.Fields{
+		"method":  "Subscriber.retry",
+		"queue":   s.queue,
+		"topic":   topic,
+		"attempt": attempt,
+	}).WithError(handleErr)
+
+	if s.retryPolicy == nil {
+		entry.Warn("Failed to handle a message")
+		return false
+	}
+
+	// The headers it was published with are kept, ours are overwritten by
+	// every attempt
+	headers := amqp.Table{}
+	for key, value := range msg.GetHeaders() {
+		headers[key] = value
+	}
+
+	headers[ATTEMPT_HEADER] = int32(attempt)
+	headers[ERROR_HEADER] = handleErr.Error()
+	headers[ORIGINAL_ROUTING_KEY_HEADER] = deliveryRoutingKey(msg)
+	headers[SUBSCRIPTION_TOPIC_HEADER] = topic
+
+	queue := deadQueueName(s.queue)
+
+	if attempt < s.retryPolicy.MaxAttempts {
+		headers[ATTEMPT_HEADER] = int32(attempt + 1)
+		queue = retryQueueName(s.queue, attempt)
+	}
+
+	channelInterface, tracker := s.getRetryChannel()
+	if channelInterface == nil || tracker == nil {
+		entry.Error("Failed to handle a message and there's no channel to retry it on")
+		return false
+	}
+
+	confirmed, err := tracker.publish(channelInterface, "", queue, false, amqp.Publishing{
+		Headers:      headers,
+		ContentType:  msg.GetContentType(),
+		DeliveryMode: amqp.Persistent,
+		MessageId:    msg.GetMessageId(),
+		Timestamp:    msg.GetTimestamp(),
+		Body:         msg.GetBody(),
+	})
+	if err == nil {
+		err = waitForConfirmation(confirmed, DEFAULT_CONFIRM_TIMEOUT)
+	}
+	if err != nil {
+		entry.WithField("publish_error", err.Error()).Error("Failed to publish a message for a retry")
+		return false
+	}
+
+	entry.WithField("retry_queue", queue).Warn("Failed to handle a message, it has been sent for a retry")
+
+	return true
+}
@@ -0,0 +1,245 @@
+package amqp
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/microplatform-io/platform"
+	. "github.com/smartystreets/goconvey/convey"
+	"github.com/streadway/amqp"
+)
+
+func TestRetryPolicyDelay(t *testing.T) {
+	Convey("The delay should double with every attempt", t, func() {
+		policy := RetryPolicy{
+			MaxAttempts: 5,
+			Delay:       time.Second,
+		}
+
+		So(policy.delay(1), ShouldEqual, time.Second)
+		So(policy.delay(2), ShouldEqual, 2*time.Second)
+		So(policy.delay(3), ShouldEqual, 4*time.Second)
+	})
+
+	Convey("The delay should never go over the max delay", t, func() {
+		policy := RetryPolicy{
+			MaxAttempts: 10,
+			Delay:       time.Second,
+			MaxDelay:    3 * time.Second,
+		}
+
+		So(policy.delay(2), ShouldEqual, 2*time.Second)
+		So(policy.delay(3), ShouldEqual, 3*time.Second)
+		So(policy.delay(9), ShouldEqual, 3*time.Second)
+	})
+}
+
+func TestSubscriberDeclareRetryQueues(t *testing.T) {
+	Convey("Declaring the retry queues should declare one queue per retry and the dead queue", t, func() {
+		subscriber, err := NewSubscriber(nil, "testing-queue", WithAtLeastOnce(), WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 3,
+			Delay:       time.Second,
+		}))
+		So(err, ShouldBeNil)
+
+		ch := newMockChannel()
+
+		So(subscriber.declareRetryQueues(ch), ShouldBeNil)
+		So(ch.mockQueueDeclares, ShouldResemble, []mockQueueDeclare{
+			mockQueueDeclare{
+				name:    "testing-queue.retry.1",
+				durable: true,
+				args: amqp.Table{
+					"x-message-ttl":             int64(1000),
+					"x-dead-letter-exchange":    "",
+					"x-dead-letter-routing-key": "testing-queue",
+				},
+			},
+			mockQueueDeclare{
+				name:    "testing-queue.retry.2",
+				durable: true,
+				args: amqp.Table{
+					"x-message-ttl":             int64(2000),
+					"x-dead-letter-exchange":    "",
+					"x-dead-letter-routing-key": "testing-queue",
+				},
+			},
+			mockQueueDeclare{
+				name:    "testing-queue.dead",
+				durable: true,
+			},
+		})
+	})
+
+	Convey("Creating an exclusive subscriber with a retry policy should return an error", t, func() {
+		subscriber, err := NewExclusiveSubscriber(nil, "", WithAtLeastOnce(), WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 3,
+			Delay:       time.Second,
+		}))
+		So(subscriber, ShouldBeNil)
+		So(err, ShouldEqual, RetriesNeedNamedQueue)
+	})
+
+	Convey("Creating a subscriber with a retry policy but without at-least-once should return an error", t, func() {
+		subscriber, err := NewSubscriber(nil, "testing-queue", WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 3,
+			Delay:       time.Second,
+		}))
+		So(subscriber, ShouldBeNil)
+		So(err, ShouldEqual, RetriesNeedAtLeastOnce)
+	})
+}
+
+func TestSubscriberRetry(t *testing.T) {
+	newRetryingSubscriber := func() (*Subscriber, *mockChannel) {
+		subscriber, _ := NewSubscriber(nil, "testing-queue", WithAtLeastOnce(), WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 3,
+			Delay:       time.Second,
+		}))
+
+		ch := newMockChannel()
+		subscriber.setChannelInterface(ch)
+		subscriber.confirmRetries(ch)
+
+		return subscriber, ch
+	}
+
+	Convey("A failed first attempt should be published to the first retry queue", t, func() {
+		subscriber, ch := newRetryingSubscriber()
+
+		So(subscriber.retry("testing.*", &mockDelivery{
+			RoutingKey:  "testing.topic",
+			ContentType: "text/plain",
+			Body:        []byte("body"),
+		}, errors.New("testing")), ShouldBeTrue)
+
+		So(ch.mockPublishes, ShouldResemble, []mockPublish{
+			mockPublish{
+				exchange: "",
+				key:      "testing-queue.retry.1",
+				msg: amqp.Publishing{
+					Headers: amqp.Table{
+						ATTEMPT_HEADER:              int32(2),
+						ERROR_HEADER:                "testing",
+						ORIGINAL_ROUTING_KEY_HEADER: "testing.topic",
+						SUBSCRIPTION_TOPIC_HEADER:   "testing.*",
+					},
+					ContentType:  "text/plain",
+					DeliveryMode: amqp.Persistent,
+					Body:         []byte("body"),
+				},
+			},
+		})
+	})
+
+	Convey("A retried message should keep the headers it was published with", t, func() {
+		subscriber, ch := newRetryingSubscriber()
+
+		subscriber.retry("testing.*", &mockDelivery{
+			RoutingKey: "testing.topic",
+			Headers: amqp.Table{
+				"origin": "testing",
+			},
+		}, errors.New("testing"))
+
+		So(len(ch.mockPublishes), ShouldEqual, 1)
+		So(ch.mockPublishes[0].msg.Headers, ShouldResemble, amqp.Table{
+			"origin":                    "testing",
+			ATTEMPT_HEADER:              int32(2),
+			ERROR_HEADER:                "testing",
+			ORIGINAL_ROUTING_KEY_HEADER: "testing.topic",
+			SUBSCRIPTION_TOPIC_HEADER:   "testing.*",
+		})
+	})
+
+	Convey("A failed last attempt should be parked in the dead queue", t, func() {
+		subscriber, ch := newRetryingSubscriber()
+
+		subscriber.retry("testing.*", &mockDelivery{
+			RoutingKey: "testing-queue",
+			Headers: amqp.Table{
+				ATTEMPT_HEADER:              int32(3),
+				ORIGINAL_ROUTING_KEY_HEADER: "testing.topic",
+				SUBSCRIPTION_TOPIC_HEADER:   "testing.*",
+			},
+			Body: []byte("body"),
+		}, errors.New("testing"))
+
+		So(len(ch.mockPublishes), ShouldEqual, 1)
+		So(ch.mockPublishes[0].key, ShouldEqual, "testing-queue.dead")
+		So(ch.mockPublishes[0].msg.Headers, ShouldResemble, amqp.Table{
+			ATTEMPT_HEADER:              int32(3),
+			ERROR_HEADER:                "testing",
+			ORIGINAL_ROUTING_KEY_HEADER: "testing.topic",
+			SUBSCRIPTION_TOPIC_HEADER:   "testing.*",
+		})
+	})
+
+	Convey("A retry that couldn't be published should be left to the delivery mode", t, func() {
+		subscriber, ch := newRetryingSubscriber()
+		ch.Close()
+
+		So(subscriber.retry("testing", &mockDelivery{}, errors.New("testing")), ShouldBeFalse)
+	})
+
+	Convey("A retry the broker nacked should be left to the delivery mode", t, func() {
+		subscriber, ch := newRetryingSubscriber()
+		ch.nackOnPublish()
+
+		So(subscriber.retry("testing", &mockDelivery{}, errors.New("testing")), ShouldBeFalse)
+		So(len(ch.mockPublishes), ShouldEqual, 1)
+	})
+
+	Convey("A failed message without a retry policy should be left to the delivery mode", t, func() {
+		subscriber, err := NewSubscriber(nil, "testing-queue")
+		So(err, ShouldBeNil)
+
+		ch := newMockChannel()
+		subscriber.setChannelInterface(ch)
+
+		So(subscriber.retry("testing", &mockDelivery{}, errors.New("testing")), ShouldBeFalse)
+
+		So(ch.mockPublishes, ShouldResemble, []mockPublish{})
+	})
+
+	Convey("A handler returning an error should send the delivery for a retry", t, func() {
+		mockDialer := newMockDialer()
+
+		subscriber, err := NewSubscriber(mockDialer, "testing-queue", WithAtLeastOnce(), WithRetryPolicy(RetryPolicy{
+			MaxAttempts: 2,
+			Delay:       time.Second,
+		}))
+		So(err, ShouldBeNil)
+
+		subscriber.Subscribe("testing-topic", platform.ConsumerHandlerFunc(func(body []byte) error {
+			return errors.New("testing")
+		}))
+
+		runEnded := make(chan bool)
+		go func() {
+			subscriber.run()
+			close(runEnded)
+		}()
+
+		<-mockDialer.getConnected()
+		time.Sleep(10 * time.Millisecond)
+
+		delivery := &mockDelivery{
+			RoutingKey: "testing-topic",
+			Body:       []byte("body"),
+		}
+		mockDialer.getConnection().channel.mockDeliveries <- delivery
+
+		time.Sleep(50 * time.Millisecond)
+		mockDialer.getConnection().Close()
+		<-runEnded
+
+		So(len(mockDialer.getConnection().channel.mockPublishes), ShouldEqual, 1)
+		So(mockDialer.getConnection().channel.mockPublishes[0].key, ShouldEqual, "testing-queue.retry.1")
+
+		// Only settled once the retry has been confirmed
+		So(mockDialer.getConnection().channel.confirmMode, ShouldBeTrue)
+		So(delivery.acked, ShouldBeTrue)
+	})
+}
@@ -1,7 +1,9 @@
 package amqp
 
 import (
+	"context"
 	"errors"
+	"sync"
 	"time"
 
 	"github.com/Sirupsen/logrus"
@@ -11,31 +13,283 @@ import (
 
 var subscriberClosed = errors.New("subscriber has been closed")
 
+type SubscriberOption func(*Subscriber)
+
+// WithPrefetch limits how many unacknowledged deliveries the broker pushes to
+// the subscriber, by count and by total body size in bytes. Zero means no limit.
+func WithPrefetch(prefetchCount, prefetchSize int) SubscriberOption {
+	return func(s *Subscriber) {
+		s.prefetchCount = prefetchCount
+		s.prefetchSize = prefetchSize
+	}
+}
+
+// WithConsumerTag identifies the subscriber's consumer on the broker, rather
+// than a generated tag. Close cancels the consumer by its tag.
+func WithConsumerTag(consumerTag string) SubscriberOption {
+	return func(s *Subscriber) {
+		s.consumerTag = consumerTag
+	}
+}
+
+// WithConsumerPriority sets the consumer's x-priority, the broker delivers to
+// the highest priority consumers of a queue first as long as they keep up.
+func WithConsumerPriority(priority int) SubscriberOption {
+	return func(s *Subscriber) {
+		if s.consumerArgs == nil {
+			s.consumerArgs = amqp.Table{}
+		}
+
+		s.consumerArgs["x-priority"] = int32(priority)
+	}
+}
+
+// WithAtLeastOnce only settles a delivery once its handlers are done with it,
+// so that nothing is lost when the subscriber dies mid-handler. Handled
+// deliveries are acked, the ones a handler returned an error for are requeued
+// unless a retry policy took care of them and the ones a handler panicked on
+// are rejected.
+func WithAtLeastOnce() SubscriberOption {
+	return func(s *Subscriber) {
+		s.atLeastOnce = true
+	}
+}
+
+// WithSubscriberReconnectPolicy spaces out the attempts to run the subscriber
+// again once it lost its connection, DefaultReconnectPolicy otherwise.
+func WithSubscriberReconnectPolicy(policy ReconnectPolicy) SubscriberOption {
+	return func(s *Subscriber) {
+		s.reconnector = newReconnector(policy)
+	}
+}
+
 type Subscriber struct {
 	dialerInterface DialerInterface
 	subscriptions   []*subscription
 	started         chan interface{}
 	closed          bool
 	quit            chan interface{}
+	reconnector     *reconnector
+
+	// Closed once the goroutine started by Run is done
+	done chan interface{}
+
+	// Closed once Close gives up on waiting for the handlers
+	abandoned chan interface{}
+
+	// Whether Close managed to cancel the consumer, so that the broker is
+	// going to close the deliveries
+	consumerCancelled bool
+
+	// The deliveries handed to the workers that haven't been settled yet
+	pending map[*pendingDelivery]bool
 
 	// Queue properties
 	queue      string
 	exclusive  bool
 	autoDelete bool
+	queueArgs  amqp.Table
+	exchange   exchange
+
+	retryPolicy *RetryPolicy
+	atLeastOnce bool
+
+	// Consumer properties
+	prefetchCount int
+	prefetchSize  int
+	consumerTag   string
+	consumerArgs  amqp.Table
+
+	// The channel of the running consumer, used to publish retries, and what
+	// matches up the confirmations of those retries
+	channelInterface ChannelInterface
+	retryTracker     *confirmTracker
+	mu               sync.Mutex
+
+	// Keeps a topic that is subscribed again from being unbound by the
+	// unsubscribe before it
+	bindMu sync.Mutex
+
+	// The topics that were unsubscribed but are still bound to the shared queue
+	unsubscribedTopics map[string]bool
+}
+
+func (s *Subscriber) ConnectionState() ConnectionState {
+	return s.reconnector.ConnectionState()
 }
 
-func (s *Subscriber) Close() error {
-	if !s.closed {
-		for i := range s.subscriptions {
-			s.subscriptions[i].Close()
+// Whether other instances may be consuming the same queue, in which case its
+// bindings aren't ours alone to remove
+func (s *Subscriber) sharesQueue() bool {
+	return s.queue != "" && !s.exclusive && !s.autoDelete
+}
+
+// Whether the delivery is only here because a topic that was unsubscribed is
+// still bound to the shared queue
+func (s *Subscriber) wasUnsubscribed(msg DeliveryInterface) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for topic := range s.unsubscribedTopics {
+		if s.exchange.matches(topic, deliveryRoutingKey(msg)) {
+			return true
 		}
+	}
+
+	return false
+}
+
+func (s *Subscriber) getChannelInterface() ChannelInterface {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.channelInterface
+}
+
+// A new channel starts out without confirms for the retries
+func (s *Subscriber) setChannelInterface(channelInterface ChannelInterface) {
+	s.mu.Lock()
+	s.channelInterface = channelInterface
+	s.retryTracker = nil
+	s.mu.Unlock()
+}
+
+func (s *Subscriber) getRetryChannel() (ChannelInterface, *confirmTracker) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.channelInterface, s.retryTracker
+}
 
-		close(s.quit)
+func (s *Subscriber) getSubscriptions() []*subscription {
+	s.mu.Lock()
+	defer s.mu.Unlock()
 
-		s.closed = true
+	return append([]*subscription{}, s.subscriptions...)
+}
+
+func (s *Subscriber) trackPending(pending *pendingDelivery) {
+	pending.onSettled = func(d *pendingDelivery) {
+		s.mu.Lock()
+		delete(s.pending, d)
+		s.mu.Unlock()
 	}
 
-	return nil
+	s.mu.Lock()
+	s.pending[pending] = true
+	s.mu.Unlock()
+}
+
+func (s *Subscriber) getPending() []*pendingDelivery {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	pending := []*pendingDelivery{}
+	for d := range s.pending {
+		pending = append(pending, d)
+	}
+
+	return pending
+}
+
+// Close shuts the subscriber down gracefully. The consumer is cancelled so
+// that the broker stops sending deliveries, the ones it sent ahead of the
+// cancel are requeued rather than handed to the workers, and the handlers that
+// are still running get until the context is done to finish. The deliveries
+// that are still unsettled by then are requeued and the context's error is
+// returned. Only WithAtLeastOnce leaves deliveries unsettled while they're
+// being handled, otherwise they were acked on dispatch and are lost.
+func (s *Subscriber) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	channelInterface := s.channelInterface
+	done := s.done
+	s.mu.Unlock()
+
+	entry := logger.WithFields(logrus.Fields{
+		"method": "Subscriber.Close",
+		"queue":  s.queue,
+	})
+
+	if channelInterface != nil {
+		if err := channelInterface.Cancel(s.consumerTag, false); err != nil {
+			entry.WithError(err).Warn("Failed to cancel the consumer")
+		} else {
+			s.mu.Lock()
+			s.consumerCancelled = true
+			s.mu.Unlock()
+		}
+	}
+
+	close(s.quit)
+
+	subscriptions := s.getSubscriptions()
+	for _, subscription := range subscriptions {
+		subscription.Close()
+	}
+
+	drained := make(chan interface{})
+	go func() {
+		if done != nil {
+			<-done
+		}
+
+		for _, subscription := range subscriptions {
+			subscription.wait()
+		}
+
+		close(drained)
+	}()
+
+	select {
+	case <-drained:
+		entry.Info("The subscriber has been drained")
+
+		return nil
+
+	case <-ctx.Done():
+		close(s.abandoned)
+
+		pending := s.getPending()
+		for _, d := range pending {
+			d.abandon()
+		}
+
+		entry.WithField("requeued", len(pending)).Warn("Gave up on waiting for the handlers to finish")
+
+		return ctx.Err()
+	}
+}
+
+// Requeues the deliveries the broker sent ahead of the consumer's cancel,
+// rather than handing them to the workers. The broker closes the deliveries
+// once they have all been read, unless the consumer couldn't be cancelled.
+func (s *Subscriber) requeueUndispatched(msgs <-chan DeliveryInterface) {
+	s.mu.Lock()
+	consumerCancelled := s.consumerCancelled
+	s.mu.Unlock()
+
+	if !consumerCancelled {
+		return
+	}
+
+	for {
+		select {
+		case msg, ok := <-msgs:
+			if !ok {
+				return
+			}
+
+			msg.Nack(false, true)
+
+		case <-s.abandoned:
+			return
+		}
+	}
 }
 
 // This method will use the subscriber's queue name as the queue to bind to
@@ -46,14 +300,16 @@ func (s *Subscriber) queueBind(channelInterface ChannelInterface) error {
 		return errors.New("Nil channel interface")
 	}
 
-	for i := range s.subscriptions {
-		logger.WithFields(logrus.Fields{
-			"queue": s.queue,
-			"topic": s.subscriptions[i].topic,
-		}).Debug("binding the queue to a topic")
+	for _, subscription := range s.getSubscriptions() {
+		for _, topic := range subscription.topics() {
+			logger.WithFields(logrus.Fields{
+				"queue": s.queue,
+				"topic": topic,
+			}).Debug("binding the queue to a topic")
 
-		if err := channelInterface.QueueBind(s.queue, s.subscriptions[i].topic, "amq.topic", false, nil); err != nil {
-			return err
+			if err := channelInterface.QueueBind(s.queue, topic, s.exchange.name, false, nil); err != nil {
+				return err
+			}
 		}
 	}
 
@@ -73,7 +329,11 @@ func (s *Subscriber) queueDeclare(channelInterface ChannelInterface) error {
 		durable = false
 	}
 
-	_, err := channelInterface.QueueDeclare(s.queue, durable, s.autoDelete, s.exclusive, false, nil)
+	if s.queueArgs["x-queue-type"] == "quorum" && (s.exclusive || s.autoDelete) {
+		return errors.New("Quorum queues can't be exclusive or auto delete")
+	}
+
+	_, err := channelInterface.QueueDeclare(s.queue, durable, s.autoDelete, s.exclusive, false, s.queueArgs)
 
 	return err
 }
@@ -104,6 +364,17 @@ func (s *Subscriber) run() error {
 
 	channelInterfaceClosed := channelInterface.NotifyClose(make(chan *amqp.Error))
 
+	// Set ahead of the binds so that a topic subscribed in the meantime is bound either way
+	s.setChannelInterface(channelInterface)
+	defer s.setChannelInterface(nil)
+
+	entry.WithField("exchange", s.exchange.name).Info("Declaring the exchange")
+
+	if err := s.exchange.declare(channelInterface); err != nil {
+		entry.WithError(err).Error("Failed to declare the exchange")
+		return err
+	}
+
 	entry.Info("Declaring the queue")
 
 	if err := s.queueDeclare(channelInterface); err != nil {
@@ -111,6 +382,20 @@ func (s *Subscriber) run() error {
 		return err
 	}
 
+	if s.retryPolicy != nil {
+		entry.Info("Declaring the retry queues")
+
+		if err := s.declareRetryQueues(channelInterface); err != nil {
+			entry.WithError(err).Error("Failed to declare the retry queues")
+			return err
+		}
+
+		if err := s.confirmRetries(channelInterface); err != nil {
+			entry.WithError(err).Error("Failed to put the channel in confirm mode for the retries")
+			return err
+		}
+	}
+
 	entry.Info("Binding the queue")
 
 	if err := s.queueBind(channelInterface); err != nil {
@@ -118,22 +403,36 @@ func (s *Subscriber) run() error {
 		return err
 	}
 
+	if s.prefetchCount > 0 || s.prefetchSize > 0 {
+		entry.WithFields(logrus.Fields{
+			"prefetch_count": s.prefetchCount,
+			"prefetch_size":  s.prefetchSize,
+		}).Info("Setting the channel's quality of service")
+
+		if err := channelInterface.Qos(s.prefetchCount, s.prefetchSize, false); err != nil {
+			entry.WithError(err).Error("Failed to set the channel's quality of service")
+			return err
+		}
+	}
+
 	entry.Info("Consuming messages from the channel interface")
 
 	msgs, err := channelInterface.Consume(
-		s.queue,     // queue
-		"",          // consumer defined by server
-		false,       // auto-ack
-		s.exclusive, // exclusive
-		false,       // no-local
-		true,        // no-wait
-		nil,         // args
+		s.queue,        // queue
+		s.consumerTag,  // consumer, defined by server when empty
+		false,          // auto-ack
+		s.exclusive,    // exclusive
+		false,          // no-local
+		true,           // no-wait
+		s.consumerArgs, // args
 	)
 	if err != nil {
 		entry.WithError(err).Error("Failed to consume messages from the channel interface")
 		return err
 	}
 
+	s.reconnector.connected()
+
 	iterate := true
 
 	if s.started != nil {
@@ -143,35 +442,62 @@ func (s *Subscriber) run() error {
 
 	for iterate {
 		select {
-		case msg := <-msgs:
-			couldHandle := false
-			wasHandled := false
+		case msg, ok := <-msgs:
+			if !ok {
+				// The consumer has been cancelled by Close, or by the broker
+				select {
+				case <-s.quit:
+					return subscriberClosed
+				default:
+				}
 
-			for _, subscription := range s.subscriptions {
-				if subscription.canHandle(msg) {
-					couldHandle = true
+				entry.Info("The deliveries have been closed")
+				iterate = false
+				break
+			}
 
-					select {
-					case subscription.deliveries <- msg:
-						wasHandled = true
+			couldHandle, wasHandled, interruption := s.dispatch(msg, connectionClosed, channelInterfaceClosed)
 
-					default:
+			if interruption != nil {
+				// Whatever wasn't handed over goes back on the queue for another consumer,
+				// pending deliveries settle themselves
+				if !s.atLeastOnce {
+					if wasHandled {
+						msg.Ack(false)
+					} else {
+						msg.Reject(true)
 					}
 				}
+
+				entry.WithField("reason", interruption.Error()).Info("Stopped while waiting for a worker")
+
+				if interruption == subscriberClosed {
+					s.requeueUndispatched(msgs)
+
+					return subscriberClosed
+				}
+
+				iterate = false
+				break
 			}
 
-			if couldHandle {
+			if !couldHandle {
+				// Another instance on the shared queue may still be subscribed
+				// to the topic, so it gets one more chance over there
+				if !msg.GetRedelivered() && s.wasUnsubscribed(msg) {
+					msg.Reject(true)
+				} else {
+					msg.Ack(false)
+
+					entry.WithField("message", msg).WithField("reason", "undeliverable").Error("Failed to handle a message to this subscriber")
+				}
+			} else if !s.atLeastOnce {
+				// Only unsubscribed subscriptions could have turned it down
 				if wasHandled {
 					msg.Ack(false)
 				} else {
 					msg.Reject(true)
-
-					entry.WithField("message", msg).WithField("reason", "busy").Warn("Failed to handle a message to this subscriber")
 				}
-			} else {
-				msg.Ack(false)
-
-				entry.WithField("message", msg).WithField("reason", "undeliverable").Error("Failed to handle a message to this subscriber")
 			}
 
 		case err := <-connectionClosed:
@@ -198,6 +524,8 @@ func (s *Subscriber) run() error {
 			}
 			iterate = false
 
+			s.requeueUndispatched(msgs)
+
 			return subscriberClosed
 		}
 	}
@@ -205,53 +533,300 @@ func (s *Subscriber) run() error {
 	return nil
 }
 
+// Hands the delivery over to every subscription that can handle it. While
+// their workers are busy this blocks, which stops us from reading further
+// deliveries and lets the prefetch limit hold the broker back, rather than
+// rejecting and requeueing the delivery.
+func (s *Subscriber) dispatch(msg DeliveryInterface, connectionClosed, channelInterfaceClosed chan *amqp.Error) (couldHandle, wasHandled bool, interruption error) {
+	subscriptions := []*subscription{}
+
+	for _, subscription := range s.getSubscriptions() {
+		if subscription.canHandle(msg) {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+
+	if len(subscriptions) <= 0 {
+		return false, false, nil
+	}
+
+	var pending *pendingDelivery
+
+	if s.atLeastOnce {
+		pending = newPendingDelivery(msg, len(subscriptions))
+		s.trackPending(pending)
+		msg = pending
+	}
+
+	for i, subscription := range subscriptions {
+		deliveries := subscription.deliveriesFor(msg)
+
+		// An idle worker takes the delivery right away, otherwise the pool is
+		// told that it kept a delivery waiting
+		select {
+		case deliveries <- msg:
+			wasHandled = true
+			continue
+
+		default:
+			subscription.deliveryWaited()
+		}
+
+		select {
+		case deliveries <- msg:
+			wasHandled = true
+			continue
+
+		// It has been unsubscribed in the meantime, the delivery goes back on
+		// the queue for whoever is still subscribed
+		case <-subscription.closing:
+			if pending != nil {
+				pending.done(requeueDelivery)
+			}
+
+			continue
+
+		case <-connectionClosed:
+			interruption = errors.New("the connection has been closed")
+
+		case <-channelInterfaceClosed:
+			interruption = errors.New("the channel has been closed")
+
+		case <-s.quit:
+			interruption = subscriberClosed
+		}
+
+		// The subscriptions that never got the delivery count towards requeueing it
+		if pending != nil {
+			for range subscriptions[i:] {
+				pending.done(requeueDelivery)
+			}
+		}
+
+		return true, wasHandled, interruption
+	}
+
+	return true, wasHandled, nil
+}
+
+// Run starts consuming in the background and blocks until everything is
+// bound, or until the reconnect policy gave up on the broker.
 func (s *Subscriber) Run() {
+	if err := s.Start(); err != nil {
+		logger.WithField("queue", s.queue).WithError(err).Error("The subscriber never started")
+	}
+}
+
+// Start is Run for callers that need to know whether the subscriber started,
+// it returns ReconnectGaveUp if the reconnect policy gave up before it could.
+func (s *Subscriber) Start() error {
 	entry := logger.WithField("method", "Subscriber.run")
 
 	entry.Debug("Initiating the run goroutine")
 
-	s.started = make(chan interface{})
+	started := make(chan interface{})
+	s.started = started
+
+	done := make(chan interface{})
+
+	s.mu.Lock()
+	s.done = done
+	s.mu.Unlock()
+
+	// Only set before started is closed
+	var startErr error
 
 	go func() {
+		defer close(done)
+
 		for {
 			entry.Debug("Calling private run method")
 
 			if err := s.run(); err != nil {
 				if err == subscriberClosed {
+					s.reconnector.disconnected()
 					return
 				}
 
-				entry.WithError(err).Error("failed to run subscription: %s", err)
+				entry.WithError(err).Error("failed to run subscription")
+			}
+
+			interval, err := s.reconnector.failed()
+			if err != nil {
+				entry.WithError(err).Error("Giving up on running the subscription")
+
+				// Nobody should be left waiting on a subscriber that will never start
+				if s.started != nil {
+					startErr = err
+					close(s.started)
+					s.started = nil
+				}
+
+				return
 			}
 
-			// Sleeping for a few milliseconds allows the close event to propagate everywhere
-			time.Sleep(10 * time.Millisecond)
+			entry.WithField("interval", interval.String()).Info("Waiting before running the subscription again")
+
+			select {
+			case <-time.After(interval):
+			case <-s.quit:
+				s.reconnector.disconnected()
+				return
+			}
 		}
 	}()
 
 	// Wait for everything to be bound
-	<-s.started
+	<-started
+
+	return startErr
+}
+
+func (s *Subscriber) Subscribe(topic string, handler platform.ConsumerHandler, options ...platform.SubscribeOption) {
+	subscription := newSubscription(topic, handler, options...)
+	subscription.exchange = s.exchange
+	subscription.failed = func(msg DeliveryInterface, err error) bool {
+		return s.retry(topic, msg, err)
+	}
+
+	s.bindMu.Lock()
+	defer s.bindMu.Unlock()
+
+	s.mu.Lock()
+	s.subscriptions = append(s.subscriptions, subscription)
+	for _, topic := range subscription.topics() {
+		delete(s.unsubscribedTopics, topic)
+	}
+	channelInterface := s.channelInterface
+	s.mu.Unlock()
+
+	// Otherwise the topics are bound the next time the subscriber runs
+	if channelInterface != nil {
+		for _, topic := range subscription.topics() {
+			if err := channelInterface.QueueBind(s.queue, topic, s.exchange.name, false, nil); err != nil {
+				logger.WithField("topic", topic).WithError(err).Error("Failed to bind the queue to a topic, it will be bound once the subscriber reconnects")
+			}
+		}
+	}
 }
 
-func (s *Subscriber) Subscribe(topic string, handler platform.ConsumerHandler) {
-	s.subscriptions = append(s.subscriptions, newSubscription(topic, handler))
+// WorkerPools reports how the worker pool of every subscription is sized
+func (s *Subscriber) WorkerPools() []platform.WorkerPool {
+	workerPools := []platform.WorkerPool{}
+
+	for _, subscription := range s.getSubscriptions() {
+		workerPools = append(workerPools, subscription.workerPool())
+	}
+
+	return workerPools
+}
+
+// Unsubscribe stops handling the topic and waits for the deliveries its
+// handlers are still working on, the ones still waiting for a worker are
+// requeued. The topic is only unbound from a queue that is private to this
+// subscriber: other instances consuming a shared queue may still be subscribed
+// to it, so its deliveries that reach this instance are requeued once for them.
+func (s *Subscriber) Unsubscribe(topic string) {
+	s.bindMu.Lock()
+
+	s.mu.Lock()
+	subscriptions := []*subscription{}
+	unsubscribed := []*subscription{}
+
+	for _, subscription := range s.subscriptions {
+		if subscription.topic == topic {
+			unsubscribed = append(unsubscribed, subscription)
+		} else {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+
+	s.subscriptions = subscriptions
+	channelInterface := s.channelInterface
+
+	// The topics the subscriptions bound along with it go away with them
+	topics := []string{}
+	unbound := map[string]bool{}
+	for _, subscription := range unsubscribed {
+		for _, topic := range subscription.topics() {
+			if !unbound[topic] {
+				unbound[topic] = true
+				topics = append(topics, topic)
+			}
+		}
+	}
+
+	if s.sharesQueue() {
+		for _, topic := range topics {
+			s.unsubscribedTopics[topic] = true
+		}
+	}
+	s.mu.Unlock()
+
+	if len(unsubscribed) <= 0 {
+		s.bindMu.Unlock()
+		return
+	}
+
+	for _, subscription := range unsubscribed {
+		subscription.Close()
+	}
+
+	if channelInterface != nil && !s.sharesQueue() {
+		for _, topic := range topics {
+			if err := channelInterface.QueueUnbind(s.queue, topic, s.exchange.name, nil); err != nil {
+				logger.WithField("topic", topic).WithError(err).Error("Failed to unbind the queue from a topic, it will stay bound until the subscriber reconnects")
+			}
+		}
+	}
+
+	s.bindMu.Unlock()
+
+	for _, subscription := range unsubscribed {
+		subscription.wait()
+	}
 }
 
-func NewSubscriber(dialerInterface DialerInterface, queue string) (*Subscriber, error) {
-	return &Subscriber{
+func newSubscriber(dialerInterface DialerInterface, queue string, exclusive, autoDelete bool, options []SubscriberOption) (*Subscriber, error) {
+	subscriber := &Subscriber{
 		dialerInterface: dialerInterface,
 		quit:            make(chan interface{}),
+		abandoned:       make(chan interface{}),
+		pending:         map[*pendingDelivery]bool{},
+		consumerTag:     platform.CreateUUID(),
 		queue:           queue,
-		exclusive:       false,
-		autoDelete:      false,
-	}, nil
+		exclusive:       exclusive,
+		autoDelete:      autoDelete,
+		exchange:        defaultExchange(),
+		reconnector:     newReconnector(DefaultReconnectPolicy),
+
+		unsubscribedTopics: map[string]bool{},
+	}
+
+	for _, option := range options {
+		option(subscriber)
+	}
+
+	if err := subscriber.exchange.validate(); err != nil {
+		return nil, err
+	}
+
+	if err := subscriber.validateRetries(); err != nil {
+		return nil, err
+	}
+
+	return subscriber, nil
+}
+
+func NewSubscriber(dialerInterface DialerInterface, queue string, options ...SubscriberOption) (*Subscriber, error) {
+	return newSubscriber(dialerInterface, queue, false, false, options)
 }
 
-func NewMultiSubscriber(dialerInterfaces []DialerInterface, queue string) (platform.Subscriber, error) {
+func NewMultiSubscriber(dialerInterfaces []DialerInterface, queue string, options ...SubscriberOption) (platform.Subscriber, error) {
 	subscribers := make([]platform.Subscriber, len(dialerInterfaces))
 
 	for i := range dialerInterfaces {
-		subscriber, err := NewSubscriber(dialerInterfaces[i], queue)
+		subscriber, err := NewSubscriber(dialerInterfaces[i], queue, options...)
 		if err != nil {
 			return nil, err
 		}
@@ -262,22 +837,10 @@ func NewMultiSubscriber(dialerInterfaces []DialerInterface, queue string) (platf
 	return platform.NewMultiSubscriber(subscribers), nil
 }
 
-func NewExclusiveSubscriber(dialerInterface DialerInterface, queue string) (*Subscriber, error) {
-	return &Subscriber{
-		dialerInterface: dialerInterface,
-		quit:            make(chan interface{}),
-		queue:           queue,
-		exclusive:       true,
-		autoDelete:      false,
-	}, nil
+func NewExclusiveSubscriber(dialerInterface DialerInterface, queue string, options ...SubscriberOption) (*Subscriber, error) {
+	return newSubscriber(dialerInterface, queue, true, false, options)
 }
 
-func NewAutoDeleteSubscriber(dialerInterface DialerInterface, queue string) (*Subscriber, error) {
-	return &Subscriber{
-		dialerInterface: dialerInterface,
-		quit:            make(chan interface{}),
-		queue:           queue,
-		exclusive:       false,
-		autoDelete:      true,
-	}, nil
+func NewAutoDeleteSubscriber(dialerInterface DialerInterface, queue string, options ...SubscriberOption) (*Subscriber, error) {
+	return newSubscriber(dialerInterface, queue, false, true, options)
 }
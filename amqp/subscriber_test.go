@@ -1,12 +1,16 @@
 package amqp
 
 import (
+	"context"
 	"errors"
+	"strconv"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/microplatform-io/platform"
 	. "github.com/smartystreets/goconvey/convey"
+	"github.com/streadway/amqp"
 )
 
 func TestSubscriberQueueBind(t *testing.T) {
@@ -83,6 +87,41 @@ func TestSubscriberQueueBind(t *testing.T) {
 			},
 		})
 	})
+
+	Convey("Binding a subscription with additional topics should bind every one of them", t, func() {
+		subscriber, err := NewSubscriber(nil, "testing-queue")
+		So(subscriber, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		subscriber.Subscribe("testing.topic", nil, platform.WithAdditionalTopics("testing-topic"))
+
+		ch := &mockChannel{
+			mockQueueBinds: []mockQueueBind{},
+		}
+
+		So(subscriber.queueBind(ch), ShouldBeNil)
+
+		So(ch.mockQueueBinds, ShouldResemble, []mockQueueBind{
+			mockQueueBind{
+				name:     "testing-queue",
+				key:      "testing.topic",
+				exchange: "amq.topic",
+				noWait:   false,
+				args:     nil,
+			},
+			mockQueueBind{
+				name:     "testing-queue",
+				key:      "testing-topic",
+				exchange: "amq.topic",
+				noWait:   false,
+				args:     nil,
+			},
+		})
+
+		So(subscriber.getSubscriptions(), ShouldHaveLength, 1)
+		So(subscriber.getSubscriptions()[0].canHandle(&mockDelivery{RoutingKey: "testing-topic"}), ShouldBeTrue)
+		So(subscriber.getSubscriptions()[0].canHandle(&mockDelivery{RoutingKey: "testing.other"}), ShouldBeFalse)
+	})
 }
 
 func TestSubscriberQueueDeclare(t *testing.T) {
@@ -176,6 +215,57 @@ func TestSubscriberQueueDeclare(t *testing.T) {
 			},
 		})
 	})
+
+	Convey("Setting queue options should pass them along as the declare args", t, func() {
+		subscriber, err := NewSubscriber(nil, "testing-queue",
+			WithMessageTTL(30*time.Second),
+			WithMaxLength(1000),
+			WithOverflow(OVERFLOW_REJECT_PUBLISH),
+			WithDeadLetterExchange("testing-dead-letters"),
+			WithLazyQueue(),
+			WithQuorumQueue(),
+		)
+		So(subscriber, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		ch := &mockChannel{
+			mockQueueDeclares: []mockQueueDeclare{},
+		}
+
+		declareErr := subscriber.queueDeclare(ch)
+		So(declareErr, ShouldBeNil)
+
+		So(ch.mockQueueDeclares, ShouldResemble, []mockQueueDeclare{
+			mockQueueDeclare{
+				name:       "testing-queue",
+				durable:    true,
+				autoDelete: false,
+				exclusive:  false,
+				noWait:     false,
+				args: amqp.Table{
+					"x-message-ttl":          int64(30000),
+					"x-max-length":           int64(1000),
+					"x-overflow":             "reject-publish",
+					"x-dead-letter-exchange": "testing-dead-letters",
+					"x-queue-mode":           "lazy",
+					"x-queue-type":           "quorum",
+				},
+			},
+		})
+	})
+
+	Convey("Declaring an exclusive quorum queue should return an error", t, func() {
+		subscriber, err := NewExclusiveSubscriber(nil, "testing-queue", WithQuorumQueue())
+		So(subscriber, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		ch := &mockChannel{
+			mockQueueDeclares: []mockQueueDeclare{},
+		}
+
+		So(subscriber.queueDeclare(ch), ShouldNotBeNil)
+		So(ch.mockQueueDeclares, ShouldResemble, []mockQueueDeclare{})
+	})
 }
 
 func TestSubscriberClose(t *testing.T) {
@@ -185,11 +275,11 @@ func TestSubscriberClose(t *testing.T) {
 		So(err, ShouldBeNil)
 
 		So(subscriber.closed, ShouldBeFalse)
-		So(subscriber.Close(), ShouldBeNil)
+		So(subscriber.Close(context.Background()), ShouldBeNil)
 		So(subscriber.closed, ShouldBeTrue)
 
 		// Ensure that repetitive calls don't cause issues
-		So(subscriber.Close(), ShouldBeNil)
+		So(subscriber.Close(context.Background()), ShouldBeNil)
 		So(subscriber.closed, ShouldBeTrue)
 	})
 
@@ -210,13 +300,123 @@ func TestSubscriberClose(t *testing.T) {
 			So(subscriber.subscriptions[i].closed, ShouldBeFalse)
 		}
 
-		So(subscriber.Close(), ShouldBeNil)
+		So(subscriber.Close(context.Background()), ShouldBeNil)
 
 		So(subscriber.closed, ShouldBeTrue)
 		for i := range subscriber.subscriptions {
 			So(subscriber.subscriptions[i].closed, ShouldBeTrue)
 		}
 	})
+
+	Convey("Closing a running subscriber should cancel the consumer and wait for its handlers", t, func() {
+		mockDialer := newMockDialer()
+
+		subscriber, err := NewSubscriber(mockDialer, "testing-queue", WithAtLeastOnce())
+		So(subscriber, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		handling := make(chan bool)
+		release := make(chan bool)
+
+		subscriber.Subscribe("testing", platform.ConsumerHandlerFunc(func(body []byte) error {
+			handling <- true
+			<-release
+
+			return nil
+		}))
+
+		subscriber.Run()
+
+		delivery := &mockDelivery{RoutingKey: "testing"}
+		mockDialer.getConnection().channel.mockDeliveries <- delivery
+		<-handling
+
+		closeErr := make(chan error)
+		go func() {
+			ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+			defer cancel()
+
+			closeErr <- subscriber.Close(ctx)
+		}()
+
+		select {
+		case <-closeErr:
+			t.Fatal("the subscriber closed while a handler was still running")
+
+		case <-time.After(20 * time.Millisecond):
+		}
+
+		So(mockDialer.getConnection().channel.getCancels(), ShouldResemble, []string{subscriber.consumerTag})
+
+		close(release)
+
+		select {
+		case err := <-closeErr:
+			So(err, ShouldBeNil)
+
+		case <-time.After(time.Second):
+			t.Fatal("the subscriber did not close in a reasonable amount of time")
+		}
+
+		So(delivery.acked, ShouldBeTrue)
+		So(subscriber.ConnectionState(), ShouldEqual, Disconnected)
+	})
+
+	Convey("Closing a subscriber should requeue the deliveries its handlers didn't finish in time", t, func() {
+		mockDialer := newMockDialer()
+
+		subscriber, err := NewSubscriber(mockDialer, "testing-queue", WithAtLeastOnce())
+		So(subscriber, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		handling := make(chan bool)
+		release := make(chan bool)
+		defer close(release)
+
+		subscriber.Subscribe("testing", platform.ConsumerHandlerFunc(func(body []byte) error {
+			handling <- true
+			<-release
+
+			return nil
+		}))
+
+		subscriber.Run()
+
+		delivery := &mockDelivery{RoutingKey: "testing"}
+		mockDialer.getConnection().channel.mockDeliveries <- delivery
+		<-handling
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		So(subscriber.Close(ctx), ShouldResemble, context.DeadlineExceeded)
+
+		So(delivery.acked, ShouldBeFalse)
+		So(delivery.nacked, ShouldBeTrue)
+		So(delivery.nackRequeue, ShouldBeTrue)
+	})
+
+	Convey("The deliveries sent ahead of the consumer's cancel should be requeued", t, func() {
+		subscriber, err := NewSubscriber(nil, "testing-queue")
+		So(subscriber, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		deliveries := []*mockDelivery{&mockDelivery{}, &mockDelivery{}}
+
+		msgs := make(chan DeliveryInterface, len(deliveries))
+		for _, delivery := range deliveries {
+			msgs <- delivery
+		}
+		close(msgs)
+
+		subscriber.consumerCancelled = true
+		subscriber.requeueUndispatched(msgs)
+
+		for _, delivery := range deliveries {
+			So(delivery.nacked, ShouldBeTrue)
+			So(delivery.nackRequeue, ShouldBeTrue)
+		}
+	})
 }
 
 func TestSubscriberRun(t *testing.T) {
@@ -247,9 +447,9 @@ func TestSubscriberRun(t *testing.T) {
 		}()
 
 		go func() {
-			<-mockDialer.connected
+			<-mockDialer.getConnected()
 			time.Sleep(10 * time.Millisecond)
-			mockDialer.connection.Close()
+			mockDialer.getConnection().Close()
 		}()
 
 		select {
@@ -261,8 +461,8 @@ func TestSubscriberRun(t *testing.T) {
 
 		So(runErr, ShouldBeNil)
 
-		So(mockDialer.totalDials, ShouldEqual, 1)
-		So(mockDialer.connection.channel.mockQueueDeclares, ShouldResemble, []mockQueueDeclare{
+		So(mockDialer.getTotalDials(), ShouldEqual, 1)
+		So(mockDialer.getConnection().channel.mockQueueDeclares, ShouldResemble, []mockQueueDeclare{
 			mockQueueDeclare{
 				name:       "testing-queue",
 				durable:    true,
@@ -271,11 +471,11 @@ func TestSubscriberRun(t *testing.T) {
 				noWait:     false,
 			},
 		})
-		So(mockDialer.connection.channel.mockQueueBinds, ShouldResemble, []mockQueueBind{})
-		So(mockDialer.connection.channel.mockConsumes, ShouldResemble, []mockConsume{
+		So(mockDialer.getConnection().channel.mockQueueBinds, ShouldResemble, []mockQueueBind{})
+		So(mockDialer.getConnection().channel.mockConsumes, ShouldResemble, []mockConsume{
 			mockConsume{
 				queue:     "testing-queue",
-				consumer:  "",
+				consumer:  subscriber.consumerTag,
 				autoAck:   false,
 				exclusive: false,
 				noLocal:   false,
@@ -308,22 +508,25 @@ func TestSubscriberRun(t *testing.T) {
 		deliveries := []*mockDelivery{}
 
 		go func() {
-			<-mockDialer.connected
+			<-mockDialer.getConnected()
 
 			time.Sleep(10 * time.Millisecond)
 
-			// We need to overflow the buffer to simulate a failed delivery, MAX_WORKERS + 1
-			for i := 0; i < MAX_WORKERS+1; i++ {
+			maxWorkers, _ := strconv.Atoi(MAX_WORKERS)
+
+			// Keep every worker busy, MAX_WORKERS + 1, so that the last delivery is still
+			// waiting for a worker when the connection goes away
+			for i := 0; i < maxWorkers+1; i++ {
 				delivery := &mockDelivery{
 					RoutingKey: "testing-topic",
 				}
 
 				deliveries = append(deliveries, delivery)
 
-				mockDialer.connection.channel.mockDeliveries <- delivery
+				mockDialer.getConnection().channel.mockDeliveries <- delivery
 			}
 
-			mockDialer.connection.Close()
+			mockDialer.getConnection().Close()
 		}()
 
 		select {
@@ -340,8 +543,8 @@ func TestSubscriberRun(t *testing.T) {
 		So(deliveries[len(deliveries)-1].ackMultiple, ShouldBeFalse)
 		So(deliveries[len(deliveries)-1].rejected, ShouldBeTrue)
 
-		So(mockDialer.totalDials, ShouldEqual, 1)
-		So(mockDialer.connection.channel.mockQueueDeclares, ShouldResemble, []mockQueueDeclare{
+		So(mockDialer.getTotalDials(), ShouldEqual, 1)
+		So(mockDialer.getConnection().channel.mockQueueDeclares, ShouldResemble, []mockQueueDeclare{
 			mockQueueDeclare{
 				name:       "testing-queue",
 				durable:    true,
@@ -350,7 +553,182 @@ func TestSubscriberRun(t *testing.T) {
 				noWait:     false,
 			},
 		})
-		So(mockDialer.connection.channel.mockQueueBinds, ShouldResemble, []mockQueueBind{
+		So(mockDialer.getConnection().channel.mockQueueBinds, ShouldResemble, []mockQueueBind{
+			mockQueueBind{
+				name:     "testing-queue",
+				key:      "testing-topic",
+				exchange: "amq.topic",
+				noWait:   false,
+			},
+		})
+		So(mockDialer.getConnection().channel.mockConsumes, ShouldResemble, []mockConsume{
+			mockConsume{
+				queue:     "testing-queue",
+				consumer:  subscriber.consumerTag,
+				autoAck:   false,
+				exclusive: false,
+				noLocal:   false,
+				noWait:    true,
+			},
+		})
+	})
+
+	Convey("Sending a message on a busy subscriber should wait for a worker rather than reject the message", t, func() {
+		mockDialer := newMockDialer()
+
+		subscriber, err := NewSubscriber(mockDialer, "testing-queue")
+		So(subscriber, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		subscriber.Subscribe("testing-topic", platform.ConsumerHandlerFunc(func(body []byte) error {
+			time.Sleep(50 * time.Millisecond)
+
+			return nil
+		}))
+
+		var runErr error
+
+		runEnded := make(chan bool)
+		go func() {
+			runErr = subscriber.run()
+			close(runEnded)
+		}()
+
+		deliveries := []*mockDelivery{}
+
+		go func() {
+			<-mockDialer.getConnected()
+
+			time.Sleep(10 * time.Millisecond)
+
+			maxWorkers, _ := strconv.Atoi(MAX_WORKERS)
+
+			for i := 0; i < maxWorkers+1; i++ {
+				delivery := &mockDelivery{
+					RoutingKey: "testing-topic",
+				}
+
+				deliveries = append(deliveries, delivery)
+
+				mockDialer.getConnection().channel.mockDeliveries <- delivery
+			}
+
+			// Leave enough time for a worker to free up and take the last delivery
+			time.Sleep(200 * time.Millisecond)
+
+			mockDialer.getConnection().Close()
+		}()
+
+		select {
+		case <-runEnded:
+
+		case <-time.After(10 * time.Second):
+			t.Fatal("subscriber did not stop running in a reasonable amount of time")
+		}
+
+		So(runErr, ShouldBeNil)
+
+		for _, delivery := range deliveries {
+			So(delivery.acked, ShouldBeTrue)
+			So(delivery.rejected, ShouldBeFalse)
+		}
+	})
+
+	Convey("An at least once subscriber should settle deliveries once they have been handled", t, func() {
+		mockDialer := newMockDialer()
+
+		subscriber, err := NewSubscriber(mockDialer, "testing-queue", WithAtLeastOnce())
+		So(subscriber, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		handling := make(chan bool)
+		release := make(chan bool)
+
+		subscriber.Subscribe("success", platform.ConsumerHandlerFunc(func(body []byte) error {
+			handling <- true
+			<-release
+
+			return nil
+		}))
+		subscriber.Subscribe("failure", platform.ConsumerHandlerFunc(func(body []byte) error {
+			return errors.New("testing")
+		}))
+		subscriber.Subscribe("panic", platform.ConsumerHandlerFunc(func(body []byte) error {
+			panic("testing")
+		}))
+		subscriber.Subscribe("#", platform.ConsumerHandlerFunc(func(body []byte) error {
+			return nil
+		}))
+
+		runEnded := make(chan bool)
+		go func() {
+			subscriber.run()
+			close(runEnded)
+		}()
+
+		<-mockDialer.getConnected()
+		time.Sleep(10 * time.Millisecond)
+
+		successDelivery := &mockDelivery{RoutingKey: "success"}
+		failureDelivery := &mockDelivery{RoutingKey: "failure"}
+		panicDelivery := &mockDelivery{RoutingKey: "panic"}
+
+		mockDialer.getConnection().channel.mockDeliveries <- successDelivery
+
+		// The delivery must not be settled while its handler is still running
+		<-handling
+		time.Sleep(10 * time.Millisecond)
+		So(successDelivery.acked, ShouldBeFalse)
+		close(release)
+
+		mockDialer.getConnection().channel.mockDeliveries <- failureDelivery
+		mockDialer.getConnection().channel.mockDeliveries <- panicDelivery
+
+		time.Sleep(50 * time.Millisecond)
+		mockDialer.getConnection().Close()
+		<-runEnded
+
+		So(successDelivery.acked, ShouldBeTrue)
+		So(successDelivery.nacked, ShouldBeFalse)
+
+		So(failureDelivery.acked, ShouldBeFalse)
+		So(failureDelivery.nacked, ShouldBeTrue)
+		So(failureDelivery.nackRequeue, ShouldBeTrue)
+
+		So(panicDelivery.acked, ShouldBeFalse)
+		So(panicDelivery.rejected, ShouldBeTrue)
+		So(panicDelivery.rejectRequeued, ShouldBeFalse)
+	})
+
+	Convey("Subscribing and unsubscribing a private queue while running should bind and unbind right away", t, func() {
+		mockDialer := newMockDialer()
+
+		subscriber, err := NewExclusiveSubscriber(mockDialer, "testing-queue")
+		So(subscriber, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		runEnded := make(chan bool)
+		go func() {
+			subscriber.run()
+			close(runEnded)
+		}()
+
+		<-mockDialer.getConnected()
+		time.Sleep(10 * time.Millisecond)
+
+		handling := make(chan bool)
+		release := make(chan bool)
+		finished := false
+
+		subscriber.Subscribe("testing-topic", platform.ConsumerHandlerFunc(func(body []byte) error {
+			handling <- true
+			<-release
+			finished = true
+
+			return nil
+		}))
+
+		So(mockDialer.getConnection().channel.mockQueueBinds, ShouldResemble, []mockQueueBind{
 			mockQueueBind{
 				name:     "testing-queue",
 				key:      "testing-topic",
@@ -358,18 +736,219 @@ func TestSubscriberRun(t *testing.T) {
 				noWait:   false,
 			},
 		})
-		So(mockDialer.connection.channel.mockConsumes, ShouldResemble, []mockConsume{
+
+		mockDialer.getConnection().channel.mockDeliveries <- &mockDelivery{
+			RoutingKey: "testing-topic",
+		}
+		<-handling
+
+		unsubscribed := make(chan bool)
+		go func() {
+			subscriber.Unsubscribe("testing-topic")
+			close(unsubscribed)
+		}()
+
+		select {
+		case <-unsubscribed:
+			t.Fatal("unsubscribe returned while the handler was still running")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		close(release)
+		<-unsubscribed
+		So(finished, ShouldBeTrue)
+
+		So(mockDialer.getConnection().channel.mockQueueUnbinds, ShouldResemble, []mockQueueUnbind{
+			mockQueueUnbind{
+				name:     "testing-queue",
+				key:      "testing-topic",
+				exchange: "amq.topic",
+			},
+		})
+		So(subscriber.getSubscriptions(), ShouldBeEmpty)
+
+		mockDialer.getConnection().Close()
+		<-runEnded
+	})
+
+	Convey("Unsubscribing from a shared queue should leave the topic bound and requeue its deliveries", t, func() {
+		mockDialer := newMockDialer()
+
+		subscriber, err := NewSubscriber(mockDialer, "testing-queue")
+		So(subscriber, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		handling := make(chan bool)
+		release := make(chan bool)
+
+		subscriber.Subscribe("testing-topic", platform.ConsumerHandlerFunc(func(body []byte) error {
+			handling <- true
+			<-release
+
+			return nil
+		}), platform.WithConcurrency(1))
+
+		runEnded := make(chan bool)
+		go func() {
+			subscriber.run()
+			close(runEnded)
+		}()
+
+		<-mockDialer.getConnected()
+		time.Sleep(10 * time.Millisecond)
+
+		handled := &mockDelivery{RoutingKey: "testing-topic"}
+		mockDialer.getConnection().channel.mockDeliveries <- handled
+		<-handling
+
+		// Waits for the only worker, which is busy with the first delivery
+		waiting := &mockDelivery{RoutingKey: "testing-topic"}
+		mockDialer.getConnection().channel.mockDeliveries <- waiting
+		time.Sleep(10 * time.Millisecond)
+
+		unsubscribed := make(chan bool)
+		go func() {
+			subscriber.Unsubscribe("testing-topic")
+			close(unsubscribed)
+		}()
+
+		time.Sleep(10 * time.Millisecond)
+		close(release)
+		<-unsubscribed
+		time.Sleep(10 * time.Millisecond)
+
+		So(handled.isAcked(), ShouldBeTrue)
+		So(waiting.isAcked(), ShouldBeFalse)
+		So(waiting.isRejected(), ShouldBeTrue)
+		So(waiting.rejectRequeued, ShouldBeTrue)
+		So(mockDialer.getConnection().channel.mockQueueUnbinds, ShouldBeEmpty)
+
+		// Another instance may still want it, but only the first time around
+		first := &mockDelivery{RoutingKey: "testing-topic"}
+		redelivered := &mockDelivery{RoutingKey: "testing-topic", Redelivered: true}
+		mockDialer.getConnection().channel.mockDeliveries <- first
+		mockDialer.getConnection().channel.mockDeliveries <- redelivered
+		time.Sleep(10 * time.Millisecond)
+
+		So(first.rejected, ShouldBeTrue)
+		So(first.rejectRequeued, ShouldBeTrue)
+		So(redelivered.rejected, ShouldBeFalse)
+		So(redelivered.acked, ShouldBeTrue)
+
+		mockDialer.getConnection().Close()
+		<-runEnded
+	})
+
+	Convey("Running a subscriber with consumer options should set the quality of service and consume with them", t, func() {
+		mockDialer := newMockDialer()
+
+		subscriber, err := NewSubscriber(mockDialer, "testing-queue", WithPrefetch(10, 0), WithConsumerTag("testing-consumer"), WithConsumerPriority(5))
+		So(subscriber, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		var runErr error
+
+		runEnded := make(chan bool)
+		go func() {
+			runErr = subscriber.run()
+			close(runEnded)
+		}()
+
+		go func() {
+			<-mockDialer.getConnected()
+			time.Sleep(10 * time.Millisecond)
+			mockDialer.getConnection().Close()
+		}()
+
+		select {
+		case <-runEnded:
+
+		case <-time.After(1000 * time.Millisecond):
+			t.Fatal("subscriber did not stop running in a reasonable amount of time")
+		}
+
+		So(runErr, ShouldBeNil)
+
+		So(mockDialer.getConnection().channel.mockQoses, ShouldResemble, []mockQos{
+			mockQos{
+				prefetchCount: 10,
+				prefetchSize:  0,
+				global:        false,
+			},
+		})
+		So(mockDialer.getConnection().channel.mockConsumes, ShouldResemble, []mockConsume{
 			mockConsume{
 				queue:     "testing-queue",
-				consumer:  "",
+				consumer:  "testing-consumer",
 				autoAck:   false,
 				exclusive: false,
 				noLocal:   false,
 				noWait:    true,
+				args: amqp.Table{
+					"x-priority": int32(5),
+				},
+			},
+		})
+	})
+
+	Convey("Running a subscriber with a custom exchange should declare it and bind to it", t, func() {
+		mockDialer := newMockDialer()
+
+		subscriber, err := NewSubscriber(mockDialer, "testing-queue", WithSubscriberExchange("testing-exchange", amqp.ExchangeTopic))
+		So(subscriber, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		subscriber.Subscribe("testing-topic", platform.ConsumerHandlerFunc(func(body []byte) error {
+			return nil
+		}))
+
+		go func() {
+			<-mockDialer.getConnected()
+			time.Sleep(10 * time.Millisecond)
+			mockDialer.getConnection().Close()
+		}()
+
+		So(subscriber.run(), ShouldBeNil)
+		So(mockDialer.getConnection().channel.mockExchangeDeclares, ShouldResemble, []mockExchangeDeclare{
+			mockExchangeDeclare{
+				name:    "testing-exchange",
+				kind:    "topic",
+				durable: true,
+			},
+		})
+		So(mockDialer.getConnection().channel.mockQueueBinds, ShouldResemble, []mockQueueBind{
+			mockQueueBind{
+				name:     "testing-queue",
+				key:      "testing-topic",
+				exchange: "testing-exchange",
+				noWait:   false,
 			},
 		})
 	})
 
+	Convey("Creating a subscriber for a headers exchange should fail", t, func() {
+		subscriber, err := NewSubscriber(newMockDialer(), "testing-queue", WithSubscriberExchange("testing-exchange", amqp.ExchangeHeaders))
+		So(subscriber, ShouldBeNil)
+		So(err, ShouldEqual, UnsupportedExchangeKind)
+	})
+
+	Convey("Running a subscriber without a prefetch should leave the quality of service alone", t, func() {
+		mockDialer := newMockDialer()
+
+		subscriber, err := NewSubscriber(mockDialer, "testing-queue")
+		So(subscriber, ShouldNotBeNil)
+		So(err, ShouldBeNil)
+
+		go func() {
+			<-mockDialer.getConnected()
+			time.Sleep(10 * time.Millisecond)
+			mockDialer.getConnection().Close()
+		}()
+
+		So(subscriber.run(), ShouldBeNil)
+		So(mockDialer.getConnection().channel.mockQoses, ShouldResemble, []mockQos{})
+	})
+
 	Convey("Running a subscriber should dial, declare, bind, and consume", t, func() {
 		mockDialer := newMockDialer()
 
@@ -377,22 +956,37 @@ func TestSubscriberRun(t *testing.T) {
 		So(subscriber, ShouldNotBeNil)
 		So(err, ShouldBeNil)
 
+		// The handlers may still be running once the subscriber stopped
+		var calledMu sync.Mutex
+		var handled sync.WaitGroup
+		handled.Add(2)
+
 		testingTopic1Called := false
 		testingTopic2Called := false
 		testingTopic3Called := false
 
 		subscriber.Subscribe("testing-topic-1", platform.ConsumerHandlerFunc(func(body []byte) error {
+			calledMu.Lock()
 			testingTopic1Called = true
+			calledMu.Unlock()
+
+			handled.Done()
 
 			return nil
 		}))
 		subscriber.Subscribe("testing-topic-2", platform.ConsumerHandlerFunc(func(body []byte) error {
+			calledMu.Lock()
 			testingTopic2Called = true
+			calledMu.Unlock()
+
+			handled.Done()
 
 			return nil
 		}))
 		subscriber.Subscribe("testing-topic-3", platform.ConsumerHandlerFunc(func(body []byte) error {
+			calledMu.Lock()
 			testingTopic3Called = true
+			calledMu.Unlock()
 
 			return nil
 		}))
@@ -418,15 +1012,15 @@ func TestSubscriberRun(t *testing.T) {
 		}
 
 		go func() {
-			<-mockDialer.connected
+			<-mockDialer.getConnected()
 
 			time.Sleep(10 * time.Millisecond)
 
-			mockDialer.connection.channel.mockDeliveries <- testingTopic1Delivery
-			mockDialer.connection.channel.mockDeliveries <- testingTopic2Delivery
-			mockDialer.connection.channel.mockDeliveries <- badRandomDelivery
+			mockDialer.getConnection().channel.mockDeliveries <- testingTopic1Delivery
+			mockDialer.getConnection().channel.mockDeliveries <- testingTopic2Delivery
+			mockDialer.getConnection().channel.mockDeliveries <- badRandomDelivery
 
-			mockDialer.connection.Close()
+			mockDialer.getConnection().Close()
 		}()
 
 		select {
@@ -438,6 +1032,22 @@ func TestSubscriberRun(t *testing.T) {
 
 		So(runErr, ShouldBeNil)
 
+		handlersDone := make(chan bool)
+		go func() {
+			handled.Wait()
+			close(handlersDone)
+		}()
+
+		select {
+		case <-handlersDone:
+
+		case <-time.After(1000 * time.Millisecond):
+			t.Fatal("the handlers were not called in a reasonable amount of time")
+		}
+
+		calledMu.Lock()
+		defer calledMu.Unlock()
+
 		// Ensure that 2 of the 3 handlers were called
 		So(testingTopic1Called, ShouldBeTrue)
 		So(testingTopic1Delivery.acked, ShouldBeTrue)
@@ -455,8 +1065,8 @@ func TestSubscriberRun(t *testing.T) {
 
 		So(testingTopic3Called, ShouldBeFalse)
 
-		So(mockDialer.totalDials, ShouldEqual, 1)
-		So(mockDialer.connection.channel.mockQueueDeclares, ShouldResemble, []mockQueueDeclare{
+		So(mockDialer.getTotalDials(), ShouldEqual, 1)
+		So(mockDialer.getConnection().channel.mockQueueDeclares, ShouldResemble, []mockQueueDeclare{
 			mockQueueDeclare{
 				name:       "testing-queue",
 				durable:    true,
@@ -465,7 +1075,7 @@ func TestSubscriberRun(t *testing.T) {
 				noWait:     false,
 			},
 		})
-		So(mockDialer.connection.channel.mockQueueBinds, ShouldResemble, []mockQueueBind{
+		So(mockDialer.getConnection().channel.mockQueueBinds, ShouldResemble, []mockQueueBind{
 			mockQueueBind{
 				name:     "testing-queue",
 				key:      "testing-topic-1",
@@ -485,10 +1095,10 @@ func TestSubscriberRun(t *testing.T) {
 				noWait:   false,
 			},
 		})
-		So(mockDialer.connection.channel.mockConsumes, ShouldResemble, []mockConsume{
+		So(mockDialer.getConnection().channel.mockConsumes, ShouldResemble, []mockConsume{
 			mockConsume{
 				queue:     "testing-queue",
-				consumer:  "",
+				consumer:  subscriber.consumerTag,
 				autoAck:   false,
 				exclusive: false,
 				noLocal:   false,
@@ -513,9 +1123,9 @@ func TestSubscriberRun(t *testing.T) {
 		}()
 
 		go func() {
-			<-mockDialer.connected
+			<-mockDialer.getConnected()
 			time.Sleep(10 * time.Millisecond)
-			mockDialer.connection.Close()
+			mockDialer.getConnection().Close()
 		}()
 
 		select {
@@ -544,9 +1154,9 @@ func TestSubscriberRun(t *testing.T) {
 		}()
 
 		go func() {
-			<-mockDialer.connected
+			<-mockDialer.getConnected()
 			time.Sleep(10 * time.Millisecond)
-			mockDialer.connection.channel.Close()
+			mockDialer.getConnection().channel.Close()
 		}()
 
 		select {
@@ -575,8 +1185,8 @@ func TestSubscriberRun(t *testing.T) {
 		}()
 
 		go func() {
-			<-mockDialer.connected
-			subscriber.Close()
+			<-mockDialer.getConnected()
+			subscriber.Close(context.Background())
 		}()
 
 		select {
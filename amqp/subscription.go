@@ -1,31 +1,106 @@
 package amqp
 
 import (
+	"math"
 	"sync"
+	"time"
 
 	"strconv"
 
 	"github.com/microplatform-io/platform"
 )
 
+// The size of the subscriptions' worker pools unless they're given one
 var MAX_WORKERS = platform.Getenv("MAX_WORKERS", "50")
 
+const (
+	// How often adaptive worker pools are resized
+	ADAPTIVE_POOL_INTERVAL = 1 * time.Second
+
+	// Adaptive pools are sized a quarter above the workers they needed
+	ADAPTIVE_POOL_HEADROOM = 1.25
+)
+
 type subscription struct {
 	topic        string
+	exchange     exchange
 	handler      platform.ConsumerHandler
 	closed       bool
+	closing      chan interface{}
 	deliveries   chan DeliveryInterface
 	totalWorkers int
+	busyWorkers  int
+	workers      sync.WaitGroup
+
+	// The number of workers the pool is meant to have, an adaptive pool moves
+	// it between min and max workers
+	poolSize   int
+	minWorkers int
+	maxWorkers int
+	adaptive   bool
+
+	// An idle worker that receives from it stops, shrinking the pool
+	shrink chan interface{}
+
+	// An ordered subscription has a single worker per lane instead, every
+	// delivery goes down the lane its ordering key picks
+	ordering *platform.SubscribeOptions
+	lanes    []chan DeliveryInterface
+
+	// What the workers went through since an adaptive pool was last resized
+	handlingTime time.Duration
+	waited       int
+
+	// Called with the deliveries the handler returned an error for, returns
+	// whether the delivery has been taken care of
+	failed func(msg DeliveryInterface, err error) bool
+
+	// Bound along with the topic, see platform.WithAdditionalTopics
+	additionalTopics []string
+
+	// PREVENT_PLATFORM_PANICS as it was when the subscription was made
+	preventPanics bool
 
 	mu sync.Mutex
 }
 
+// Matches the delivery's routing key the same way the exchange matched it
+// against the queue binding, so that every delivery the binding let through
+// ends up at the right subscriptions. A retried delivery only goes back to the
+// subscription that failed it.
 func (s *subscription) canHandle(msg DeliveryInterface) bool {
-	if s.topic == "" || s.topic == "#" {
+	if topic, ok := msg.GetHeaders()[SUBSCRIPTION_TOPIC_HEADER].(string); ok {
+		return s.topic == topic
+	}
+
+	if s.topic == "" {
 		return true
 	}
 
-	return s.topic == msg.GetRoutingKey()
+	routingKey := deliveryRoutingKey(msg)
+
+	for _, topic := range s.topics() {
+		if s.exchange.matches(topic, routingKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Every topic the queue is bound to for the subscription
+func (s *subscription) topics() []string {
+	return append([]string{s.topic}, s.additionalTopics...)
+}
+
+// Where the delivery is handed over to the workers. A lane that is still busy
+// holds up the deliveries after it, just like a pool without idle workers.
+func (s *subscription) deliveriesFor(msg DeliveryInterface) chan DeliveryInterface {
+	if len(s.lanes) <= 0 {
+		return s.deliveries
+	}
+
+	return s.lanes[s.ordering.Lane(msg.GetBody(), deliveryMetadata(msg))]
 }
 
 func (s *subscription) Close() error {
@@ -33,20 +108,205 @@ func (s *subscription) Close() error {
 	defer s.mu.Unlock()
 
 	if !s.closed {
-		close(s.deliveries)
+		close(s.closing)
 		s.closed = true
 	}
 
 	return nil
 }
 
+// Blocks until every worker of a closed subscription is done with the delivery
+// it was handling
+func (s *subscription) wait() {
+	s.workers.Wait()
+}
+
+func (s *subscription) getTotalWorkers() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.totalWorkers
+}
+
+func (s *subscription) workerPool() platform.WorkerPool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return platform.WorkerPool{
+		Topic:   s.topic,
+		Size:    s.totalWorkers,
+		Busy:    s.busyWorkers,
+		MinSize: s.minWorkers,
+		MaxSize: s.maxWorkers,
+	}
+}
+
+// Called by the subscriber when a delivery had to wait for a free worker
+func (s *subscription) deliveryWaited() {
+	s.mu.Lock()
+	s.waited++
+	s.mu.Unlock()
+}
+
+func (s *subscription) startWorkers(total int) {
+	s.workers.Add(total)
+
+	for i := 0; i < total; i++ {
+		go func() {
+			defer s.workers.Done()
+
+			s.runWorker()
+		}()
+	}
+}
+
+// Sizes an adaptive pool after the workers it needed over the last interval.
+// By Little's law that is the rate of deliveries times how long handling one
+// took, which comes down to the time spent handling them over the interval.
+// Deliveries that had to wait for a free worker mean the pool needs at least
+// one more. The pool grows right away but only shrinks by a quarter at a time,
+// so that a short lull doesn't throw all of its workers away.
+func (s *subscription) resize(interval time.Duration) {
+	s.mu.Lock()
+	needed := math.Max(float64(s.handlingTime)/float64(interval), float64(s.busyWorkers))
+	target := int(math.Ceil(needed * ADAPTIVE_POOL_HEADROOM))
+
+	if s.waited > 0 && target <= s.poolSize {
+		target = s.poolSize + 1
+	}
+
+	if target < s.minWorkers {
+		target = s.minWorkers
+	}
+
+	if target > s.maxWorkers {
+		target = s.maxWorkers
+	}
+
+	s.handlingTime = 0
+	s.waited = 0
+
+	grow := target - s.poolSize
+	if grow > 0 {
+		s.poolSize = target
+	}
+
+	shrink := 0
+	if grow < 0 {
+		shrink = s.poolSize / 4
+		if shrink < 1 {
+			shrink = 1
+		}
+
+		if s.poolSize-shrink < target {
+			shrink = s.poolSize - target
+		}
+	}
+	s.mu.Unlock()
+
+	if grow > 0 {
+		s.startWorkers(grow)
+		return
+	}
+
+	// Only the idle workers are listening, busy ones are left alone
+	for i := 0; i < shrink; i++ {
+		select {
+		case s.shrink <- struct{}{}:
+			s.mu.Lock()
+			s.poolSize--
+			s.mu.Unlock()
+
+		default:
+			return
+		}
+	}
+}
+
+func (s *subscription) runScaler() {
+	defer s.workers.Done()
+
+	ticker := time.NewTicker(ADAPTIVE_POOL_INTERVAL)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			s.resize(ADAPTIVE_POOL_INTERVAL)
+
+		case <-s.closing:
+			return
+		}
+	}
+}
+
+// Handles a single delivery. A panicking handler rejects the delivery without
+// requeueing it, unless PREVENT_PLATFORM_PANICS was disabled when the
+// subscription was made, in which case the panic is left alone.
+func (s *subscription) handle(msg DeliveryInterface) (outcome deliveryOutcome) {
+	defer func() {
+		if !s.preventPanics {
+			return
+		}
+
+		if r := recover(); r != nil {
+			logger.WithField("topic", s.topic).WithField("panic", r).Error("The handler panicked while handling a message")
+
+			outcome = rejectDelivery
+		}
+	}()
+
+	if err := platform.HandleMessageWithMetadata(s.handler, msg.GetBody(), deliveryMetadata(msg)); err != nil {
+		if s.failed != nil && s.failed(msg, err) {
+			return ackDelivery
+		}
+
+		return requeueDelivery
+	}
+
+	return ackDelivery
+}
+
 func (s *subscription) runWorker() {
+	s.work(s.deliveries)
+}
+
+func (s *subscription) work(deliveries chan DeliveryInterface) {
 	s.mu.Lock()
 	s.totalWorkers += 1
 	s.mu.Unlock()
 
-	for msg := range s.deliveries {
-		s.handler.HandleMessage(msg.GetBody())
+	for iterate := true; iterate; {
+		select {
+		case msg, ok := <-deliveries:
+			if !ok {
+				iterate = false
+				break
+			}
+
+			s.mu.Lock()
+			s.busyWorkers += 1
+			s.mu.Unlock()
+
+			startedAt := time.Now()
+
+			outcome := s.handle(msg)
+
+			s.mu.Lock()
+			s.busyWorkers -= 1
+			s.handlingTime += time.Since(startedAt)
+			s.mu.Unlock()
+
+			if pending, ok := msg.(*pendingDelivery); ok {
+				pending.done(outcome)
+			}
+
+		case <-s.shrink:
+			iterate = false
+
+		case <-s.closing:
+			iterate = false
+		}
 	}
 
 	s.mu.Lock()
@@ -54,22 +314,166 @@ func (s *subscription) runWorker() {
 	s.mu.Unlock()
 }
 
-func newSubscription(topic string, handler platform.ConsumerHandler) *subscription {
+// Describes the delivery in the transport-neutral terms handlers get to see.
+// A retried delivery keeps the routing key it was originally published with.
+func deliveryMetadata(msg DeliveryInterface) *platform.MessageMetadata {
+	headers := map[string]interface{}{}
+	for key, value := range msg.GetHeaders() {
+		headers[key] = value
+	}
+
+	return &platform.MessageMetadata{
+		MessageId:   msg.GetMessageId(),
+		RoutingKey:  deliveryRoutingKey(msg),
+		Headers:     headers,
+		Redelivered: msg.GetRedelivered(),
+		Timestamp:   msg.GetTimestamp(),
+	}
+}
+
+type deliveryOutcome int
+
+// Ordered from best to worst, a delivery handed to several subscriptions is
+// settled with the worst of their outcomes
+const (
+	ackDelivery deliveryOutcome = iota
+	requeueDelivery
+	rejectDelivery
+)
+
+// A delivery that is only settled once every subscription it was handed to
+// is done handling it.
+type pendingDelivery struct {
+	DeliveryInterface
+
+	mu        sync.Mutex
+	remaining int
+	outcome   deliveryOutcome
+	settled   bool
+
+	// Called once the delivery has been settled, one way or another
+	onSettled func(d *pendingDelivery)
+}
+
+func (d *pendingDelivery) done(outcome deliveryOutcome) {
+	d.mu.Lock()
+	if outcome > d.outcome {
+		d.outcome = outcome
+	}
+	d.remaining--
+	settle := d.remaining == 0 && !d.settled
+	if settle {
+		d.settled = true
+	}
+	d.mu.Unlock()
+
+	if !settle {
+		return
+	}
+
+	switch d.outcome {
+	case ackDelivery:
+		d.Ack(false)
+	case requeueDelivery:
+		d.Nack(false, true)
+	case rejectDelivery:
+		d.Reject(false)
+	}
+
+	if d.onSettled != nil {
+		d.onSettled(d)
+	}
+}
+
+// Requeues the delivery without waiting for its subscriptions any longer,
+// whatever they end up doing with it is ignored
+func (d *pendingDelivery) abandon() {
+	d.mu.Lock()
+	abandon := !d.settled
+	d.settled = true
+	d.mu.Unlock()
+
+	if !abandon {
+		return
+	}
+
+	d.Nack(false, true)
+
+	if d.onSettled != nil {
+		d.onSettled(d)
+	}
+}
+
+func newPendingDelivery(msg DeliveryInterface, handlers int) *pendingDelivery {
+	return &pendingDelivery{
+		DeliveryInterface: msg,
+		remaining:         handlers,
+	}
+}
+
+// The pool has a fixed number of workers, MAX_WORKERS unless the options size
+// it, or is adaptive and starts at its min workers. An ordered subscription
+// has a worker per lane.
+func newSubscription(topic string, handler platform.ConsumerHandler, options ...platform.SubscribeOption) *subscription {
 	s := &subscription{
-		topic:        topic,
-		handler:      handler,
-		deliveries:   make(chan DeliveryInterface),
-		totalWorkers: 0,
+		topic:         topic,
+		handler:       handler,
+		closing:       make(chan interface{}),
+		deliveries:    make(chan DeliveryInterface),
+		shrink:        make(chan interface{}),
+		totalWorkers:  0,
+		preventPanics: platform.PREVENT_PLATFORM_PANICS,
+	}
+
+	subscribeOptions := platform.NewSubscribeOptions(options...)
+
+	s.additionalTopics = subscribeOptions.AdditionalTopics
+
+	if subscribeOptions.Ordered() {
+		s.ordering = subscribeOptions
+		s.lanes = make([]chan DeliveryInterface, subscribeOptions.Lanes)
+		s.minWorkers = subscribeOptions.Lanes
+		s.maxWorkers = subscribeOptions.Lanes
+		s.poolSize = subscribeOptions.Lanes
+
+		s.workers.Add(len(s.lanes))
+
+		for i := range s.lanes {
+			s.lanes[i] = make(chan DeliveryInterface)
+
+			go func(lane chan DeliveryInterface) {
+				defer s.workers.Done()
+
+				s.work(lane)
+			}(s.lanes[i])
+		}
+
+		return s
 	}
 
-	maxWorkers, err := strconv.Atoi(MAX_WORKERS)
-	if err != nil {
-		maxWorkers = 50
+	if subscribeOptions.Adaptive {
+		s.adaptive = true
+		s.minWorkers = subscribeOptions.MinConcurrency
+		s.maxWorkers = subscribeOptions.MaxConcurrency
+	} else {
+		poolSize := subscribeOptions.Concurrency
+		if poolSize <= 0 {
+			var err error
+			if poolSize, err = strconv.Atoi(MAX_WORKERS); err != nil {
+				poolSize = 50
+			}
+		}
+
+		s.minWorkers = poolSize
+		s.maxWorkers = poolSize
 	}
 
-	// TODO: Determine an ideal worker pool
-	for i := 0; i < maxWorkers; i++ {
-		go s.runWorker()
+	s.poolSize = s.minWorkers
+	s.startWorkers(s.poolSize)
+
+	if s.adaptive {
+		s.workers.Add(1)
+		go s.runScaler()
 	}
 
 	return s
@@ -1,11 +1,15 @@
 package amqp
 
 import (
+	"strconv"
+	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/microplatform-io/platform"
 	. "github.com/smartystreets/goconvey/convey"
+	"github.com/streadway/amqp"
 )
 
 func TestSubscriptionCanHandle(t *testing.T) {
@@ -32,11 +36,67 @@ func TestSubscriptionCanHandle(t *testing.T) {
 			RoutingKey: "success",
 		}), ShouldBeTrue)
 	})
+
+	Convey("A subscription with a wildcard topic should handle the deliveries its binding matches", t, func() {
+		for _, testCase := range []struct {
+			topic      string
+			routingKey string
+			canHandle  bool
+		}{
+			{"#", "orders.created", true},
+			{"orders.*", "orders.created", true},
+			{"orders.*", "orders.created.today", false},
+			{"events.#.created", "events.created", true},
+			{"events.#.created", "events.user.account.created", true},
+			{"events.#.created", "events.user.account.deleted", false},
+			{"microservice.users.*.orders", "microservice.users.42.orders", true},
+		} {
+			subscription := &subscription{
+				topic: testCase.topic,
+			}
+
+			So(subscription.canHandle(&mockDelivery{
+				RoutingKey: testCase.routingKey,
+			}), ShouldEqual, testCase.canHandle)
+		}
+	})
+
+	Convey("A subscription should match deliveries the way its exchange routes them", t, func() {
+		direct := &subscription{
+			topic:    "events.*",
+			exchange: exchange{name: "testing-exchange", kind: amqp.ExchangeDirect},
+		}
+
+		So(direct.canHandle(&mockDelivery{RoutingKey: "events.created"}), ShouldBeFalse)
+		So(direct.canHandle(&mockDelivery{RoutingKey: "events.*"}), ShouldBeTrue)
+
+		fanout := &subscription{
+			topic:    "events.*",
+			exchange: exchange{name: "testing-exchange", kind: amqp.ExchangeFanout},
+		}
+
+		So(fanout.canHandle(&mockDelivery{RoutingKey: "anything"}), ShouldBeTrue)
+	})
+
+	Convey("A retried delivery should only go back to the subscription that failed it", t, func() {
+		delivery := &mockDelivery{
+			RoutingKey: "testing-queue",
+			Headers: amqp.Table{
+				ORIGINAL_ROUTING_KEY_HEADER: "orders.created",
+				SUBSCRIPTION_TOPIC_HEADER:   "orders.*",
+			},
+		}
+
+		So((&subscription{topic: "orders.*"}).canHandle(delivery), ShouldBeTrue)
+		So((&subscription{topic: "orders.created"}).canHandle(delivery), ShouldBeFalse)
+		So((&subscription{topic: "#"}).canHandle(delivery), ShouldBeFalse)
+	})
 }
 
 func TestSubscriptionRunWorker(t *testing.T) {
 	Convey("Running a subscription with a msg chan that immediately closes should return", t, func() {
 		subscription := &subscription{
+			closing:    make(chan interface{}),
 			deliveries: make(chan DeliveryInterface),
 		}
 
@@ -49,7 +109,7 @@ func TestSubscriptionRunWorker(t *testing.T) {
 
 		time.Sleep(10 * time.Millisecond)
 
-		So(subscription.totalWorkers, ShouldEqual, 1)
+		So(subscription.getTotalWorkers(), ShouldEqual, 1)
 
 		subscription.Close()
 
@@ -60,7 +120,7 @@ func TestSubscriptionRunWorker(t *testing.T) {
 			t.Error("subscription did not finish running in a reasonable amount of time")
 		}
 
-		So(subscription.totalWorkers, ShouldEqual, 0)
+		So(subscription.getTotalWorkers(), ShouldEqual, 0)
 	})
 
 	Convey("Running a subscription that receives a message should call the handler", t, func() {
@@ -107,10 +167,233 @@ func TestNewSubscription(t *testing.T) {
 			return nil
 		}))
 
-		So(subscription.totalWorkers, ShouldBeLessThan, MAX_WORKERS)
+		maxWorkers, _ := strconv.Atoi(MAX_WORKERS)
+
+		// The workers are started in the background, give them a moment to register
+		for i := 0; i < 100 && subscription.getTotalWorkers() < maxWorkers; i++ {
+			time.Sleep(time.Millisecond)
+		}
+
+		So(subscription.getTotalWorkers(), ShouldEqual, maxWorkers)
+	})
+}
+
+func TestPendingDelivery(t *testing.T) {
+	Convey("A pending delivery should only be settled once every subscription is done with it", t, func() {
+		delivery := &mockDelivery{}
+		pending := newPendingDelivery(delivery, 2)
+
+		pending.done(ackDelivery)
+		So(delivery.acked, ShouldBeFalse)
+
+		pending.done(ackDelivery)
+		So(delivery.acked, ShouldBeTrue)
+	})
+
+	Convey("A pending delivery should be settled with the worst outcome of its subscriptions", t, func() {
+		delivery := &mockDelivery{}
+		pending := newPendingDelivery(delivery, 3)
+
+		pending.done(requeueDelivery)
+		pending.done(rejectDelivery)
+		pending.done(ackDelivery)
+
+		So(delivery.acked, ShouldBeFalse)
+		So(delivery.nacked, ShouldBeFalse)
+		So(delivery.rejected, ShouldBeTrue)
+		So(delivery.rejectRequeued, ShouldBeFalse)
+	})
+}
+
+func TestSubscriptionHandleMetadata(t *testing.T) {
+	Convey("A metadata consumer handler should be handed the delivery's metadata", t, func() {
+		timestamp := time.Now()
+
+		var received *platform.MessageMetadata
+
+		subscription := &subscription{
+			topic: "events.*",
+			handler: platform.MetadataConsumerHandlerFunc(func(body []byte, metadata *platform.MessageMetadata) error {
+				received = metadata
+				return nil
+			}),
+		}
+
+		So(subscription.handle(&mockDelivery{
+			Headers:     amqp.Table{"origin": "testing"},
+			MessageId:   "message-id",
+			Timestamp:   timestamp,
+			Redelivered: true,
+			RoutingKey:  "events.created",
+		}), ShouldEqual, ackDelivery)
+
+		So(received, ShouldResemble, &platform.MessageMetadata{
+			MessageId:   "message-id",
+			RoutingKey:  "events.created",
+			Headers:     map[string]interface{}{"origin": "testing"},
+			Redelivered: true,
+			Timestamp:   timestamp,
+		})
+	})
+
+	Convey("A retried delivery should be described by the routing key it was originally published with", t, func() {
+		metadata := deliveryMetadata(&mockDelivery{
+			Headers: amqp.Table{
+				ORIGINAL_ROUTING_KEY_HEADER: "events.created",
+			},
+			RoutingKey: "testing-queue",
+		})
+
+		So(metadata.RoutingKey, ShouldEqual, "events.created")
+	})
+}
+
+func TestSubscriptionHandlePanics(t *testing.T) {
+	panicking := func(preventPanics bool) *subscription {
+		return &subscription{
+			topic: "testing",
+			handler: platform.ConsumerHandlerFunc(func(body []byte) error {
+				panic("testing")
+			}),
+			preventPanics: preventPanics,
+		}
+	}
+
+	Convey("A panicking handler should reject the delivery when panics are prevented", t, func() {
+		So(panicking(true).handle(&mockDelivery{RoutingKey: "testing"}), ShouldEqual, rejectDelivery)
+	})
+
+	Convey("A panicking handler should be left to panic when panics aren't prevented", t, func() {
+		So(func() { panicking(false).handle(&mockDelivery{RoutingKey: "testing"}) }, ShouldPanic)
+	})
+
+	Convey("A subscription should prevent panics as PREVENT_PLATFORM_PANICS was when it was made", t, func() {
+		subscription := newSubscription("testing", platform.ConsumerHandlerFunc(func(body []byte) error {
+			return nil
+		}))
+		defer subscription.Close()
+
+		So(subscription.preventPanics, ShouldEqual, platform.PREVENT_PLATFORM_PANICS)
+	})
+}
+
+func TestSubscriptionWorkerPool(t *testing.T) {
+	Convey("A subscription with a concurrency should run that many workers", t, func() {
+		subscription := newSubscription("testing-topic", platform.ConsumerHandlerFunc(func(body []byte) error {
+			return nil
+		}), platform.WithConcurrency(3))
+		defer subscription.Close()
+
+		for i := 0; i < 100 && subscription.getTotalWorkers() < 3; i++ {
+			time.Sleep(time.Millisecond)
+		}
+
+		So(subscription.workerPool(), ShouldResemble, platform.WorkerPool{
+			Topic:   "testing-topic",
+			Size:    3,
+			Busy:    0,
+			MinSize: 3,
+			MaxSize: 3,
+		})
+	})
+
+	Convey("An adaptive subscription should grow while deliveries wait and shrink back once idle", t, func() {
+		subscription := newSubscription("testing-topic", platform.ConsumerHandlerFunc(func(body []byte) error {
+			return nil
+		}), platform.WithAdaptiveConcurrency(1, 4))
+		defer subscription.Close()
+
+		waitForWorkers := func(total int) int {
+			for i := 0; i < 100 && subscription.getTotalWorkers() != total; i++ {
+				time.Sleep(time.Millisecond)
+			}
+
+			return subscription.getTotalWorkers()
+		}
+
+		So(waitForWorkers(1), ShouldEqual, 1)
+
+		// A delivery had to wait even though the worker wasn't kept busy
+		subscription.deliveryWaited()
+		subscription.resize(time.Second)
+		So(waitForWorkers(2), ShouldEqual, 2)
+
+		// Four seconds of handling within a second takes four workers, and no more
+		subscription.mu.Lock()
+		subscription.handlingTime = 4 * time.Second
+		subscription.mu.Unlock()
+
+		subscription.resize(time.Second)
+		So(waitForWorkers(4), ShouldEqual, 4)
+
+		subscription.resize(time.Second)
+		So(waitForWorkers(3), ShouldEqual, 3)
+
+		subscription.resize(time.Second)
+		subscription.resize(time.Second)
+		So(waitForWorkers(1), ShouldEqual, 1)
+
+		subscription.resize(time.Second)
+		So(waitForWorkers(1), ShouldEqual, 1)
+
+		So(subscription.workerPool().MinSize, ShouldEqual, 1)
+		So(subscription.workerPool().MaxSize, ShouldEqual, 4)
+	})
+}
+
+func TestSubscriptionOrdering(t *testing.T) {
+	Convey("An ordered subscription should handle the deliveries of a key in order", t, func() {
+		var mu sync.Mutex
+		handled := map[string][]string{}
+
+		byAccount := func(body []byte, metadata *platform.MessageMetadata) string {
+			return strings.Split(string(body), ":")[0]
+		}
+
+		subscription := newSubscription("testing-topic", platform.ConsumerHandlerFunc(func(body []byte) error {
+			parts := strings.Split(string(body), ":")
+
+			// The first deliveries take the longest, they'd be overtaken if they weren't ordered
+			sequence, _ := strconv.Atoi(parts[1])
+			time.Sleep(time.Duration(10-sequence) * time.Millisecond)
+
+			mu.Lock()
+			handled[parts[0]] = append(handled[parts[0]], parts[1])
+			mu.Unlock()
+
+			return nil
+		}), platform.WithOrdering(4, byAccount))
+		defer subscription.Close()
+
+		So(len(subscription.lanes), ShouldEqual, 4)
+		So(subscription.workerPool().MaxSize, ShouldEqual, 4)
+
+		for i := 0; i < 10; i++ {
+			for _, account := range []string{"first", "second"} {
+				delivery := &mockDelivery{Body: []byte(account + ":" + strconv.Itoa(i))}
+
+				subscription.deliveriesFor(delivery) <- delivery
+			}
+		}
+
+		expected := []string{"0", "1", "2", "3", "4", "5", "6", "7", "8", "9"}
+
+		for i := 0; i < 1000; i++ {
+			mu.Lock()
+			total := len(handled["first"]) + len(handled["second"])
+			mu.Unlock()
+
+			if total == 20 {
+				break
+			}
+
+			time.Sleep(time.Millisecond)
+		}
 
-		time.Sleep(time.Millisecond)
+		mu.Lock()
+		defer mu.Unlock()
 
-		So(subscription.totalWorkers, ShouldEqual, MAX_WORKERS)
+		So(handled["first"], ShouldResemble, expected)
+		So(handled["second"], ShouldResemble, expected)
 	})
 }
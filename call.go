@@ -0,0 +1,203 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/pkg/errors"
+)
+
+// ResourceUri returns the resource URI that replies carrying the message are
+// routed to, following the resource:///<package>/reply/<message> convention,
+// e.g. resource:///platform/reply/error for an Error.
+func ResourceUri(msg Message) string {
+	messageName := proto.MessageName(msg)
+
+	// Fall back on the go type for messages that were never registered
+	if messageName == "" {
+		messageType := reflect.TypeOf(msg)
+		for messageType.Kind() == reflect.Ptr {
+			messageType = messageType.Elem()
+		}
+
+		messageName = path.Base(messageType.PkgPath()) + "." + messageType.Name()
+	}
+
+	packageName := ""
+	if i := strings.LastIndex(messageName, "."); i >= 0 {
+		packageName, messageName = messageName[:i], messageName[i+1:]
+	}
+
+	return "resource:///" + strings.ToLower(strings.Replace(packageName, ".", "/", -1)) + "/reply/" + snakeCase(messageName)
+}
+
+func snakeCase(s string) string {
+	runes := []rune(s)
+	result := []rune{}
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word on a lower to upper transition, or at the end of an acronym
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				result = append(result, '_')
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		result = append(result, r)
+	}
+
+	return string(result)
+}
+
+type CallOption func(*callOptions)
+
+type callOptions struct {
+	responseUri string
+}
+
+// WithResponseUri expects the replies on the given resource uri, for services
+// whose replies don't follow the convention ResourceUri derives them from.
+func WithResponseUri(uri string) CallOption {
+	return func(options *callOptions) {
+		options.responseUri = uri
+	}
+}
+
+// The resource uri the replies are expected on
+func expectedResponseUri(response Message, options []CallOption) string {
+	callOptions := &callOptions{}
+	for _, option := range options {
+		option(callOptions)
+	}
+
+	if callOptions.responseUri != "" {
+		return callOptions.responseUri
+	}
+
+	return ResourceUri(response)
+}
+
+func newCallRequest(uri string, request Message) (*Request, error) {
+	payload, err := Marshal(request)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the request payload")
+	}
+
+	return &Request{
+		Routing: RouteToUri(uri),
+		Payload: payload,
+	}, nil
+}
+
+// Turns error replies into a *PlatformError and otherwise decodes the payload
+// into the response, as long as the reply is the resource we were expecting.
+func decodeCallResponse(routedResponse *Request, response Message, expectedUri string) error {
+	if platformError := NewPlatformErrorFromResponse(routedResponse); platformError != nil {
+		return platformError
+	}
+
+	responseUri := ""
+	if len(routedResponse.GetRouting().GetRouteTo()) > 0 {
+		responseUri = routedResponse.GetRouting().GetRouteTo()[0].GetUri()
+	}
+
+	if responseUri != expectedUri {
+		return fmt.Errorf("unexpected response resource %s, expected %s", responseUri, expectedUri)
+	}
+
+	if err := Unmarshal(routedResponse.GetPayload(), response); err != nil {
+		return errors.Wrap(err, "failed to unmarshal the response payload")
+	}
+
+	return nil
+}
+
+func isStreamTerminator(routedResponse *Request) bool {
+	return routedResponse.GetCompleted() && routedResponse.Payload == nil
+}
+
+// Call routes the request to the uri and decodes the completed reply into the
+// response. Error replies are returned as a *PlatformError.
+func Call(router Router, uri string, request Message, response Message, options ...CallOption) error {
+	return CallContext(context.Background(), router, uri, request, response, options...)
+}
+
+func CallContext(ctx context.Context, router Router, uri string, request Message, response Message, options ...CallOption) error {
+	callRequest, err := newCallRequest(uri, request)
+	if err != nil {
+		return err
+	}
+
+	routedResponse, err := router.RouteContext(ctx, callRequest)
+	if err != nil {
+		return err
+	}
+
+	return decodeCallResponse(routedResponse, response, expectedResponseUri(response, options))
+}
+
+// CallStream routes the request to the uri and decodes every reply into the
+// response, invoking fn after each one until the stream completes or fn
+// returns an error. Heartbeats are skipped.
+func CallStream(router Router, uri string, request Message, response Message, fn func() error, options ...CallOption) error {
+	return CallStreamContext(context.Background(), router, uri, request, response, fn, options...)
+}
+
+func CallStreamContext(ctx context.Context, router Router, uri string, request Message, response Message, fn func() error, options ...CallOption) error {
+	callRequest, err := newCallRequest(uri, request)
+	if err != nil {
+		return err
+	}
+
+	expectedUri := expectedResponseUri(response, options)
+
+	// Returning early cancels the stream, so the router stops waiting on it and
+	// lets the service know
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	responses, streamTimeout := router.StreamContext(ctx, callRequest)
+
+	for {
+		select {
+		case routedResponse := <-responses:
+			if len(routedResponse.GetRouting().GetRouteTo()) > 0 && routedResponse.GetRouting().GetRouteTo()[0].GetUri() == "resource:///heartbeat" {
+				continue
+			}
+
+			// A stream that sent nothing is completed without a payload, see MessageStream.Close
+			if isStreamTerminator(routedResponse) {
+				return nil
+			}
+
+			if err := decodeCallResponse(routedResponse, response, expectedUri); err != nil {
+				return err
+			}
+
+			if err := fn(); err != nil {
+				return err
+			}
+
+			if routedResponse.GetCompleted() {
+				return nil
+			}
+
+		case <-streamTimeout:
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			return RequestTimeout
+
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
@@ -0,0 +1,210 @@
+package platform
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type mockRouter struct {
+	requests  []*Request
+	responses []*Request
+
+	// The context of the last stream
+	ctx context.Context
+}
+
+func (r *mockRouter) Route(request *Request) (*Request, error) {
+	return r.RouteContext(context.Background(), request)
+}
+
+func (r *mockRouter) RouteContext(ctx context.Context, request *Request) (*Request, error) {
+	r.requests = append(r.requests, request)
+
+	return r.responses[len(r.responses)-1], nil
+}
+
+func (r *mockRouter) Stream(request *Request) (chan *Request, chan interface{}) {
+	return r.StreamContext(context.Background(), request)
+}
+
+func (r *mockRouter) StreamContext(ctx context.Context, request *Request) (chan *Request, chan interface{}) {
+	r.requests = append(r.requests, request)
+	r.ctx = ctx
+
+	responses := make(chan *Request, len(r.responses))
+	for i := range r.responses {
+		responses <- r.responses[i]
+	}
+
+	return responses, make(chan interface{})
+}
+
+func (r *mockRouter) SetHeartbeatTimeout(heartbeatTimeout time.Duration) {}
+
+func newMockRouter(responses ...*Request) *mockRouter {
+	return &mockRouter{
+		requests:  []*Request{},
+		responses: responses,
+	}
+}
+
+func newMockResourceResponse(msg Message, completed bool) *Request {
+	payload, _ := Marshal(msg)
+
+	return &Request{
+		Routing:   RouteToUri(ResourceUri(msg)),
+		Payload:   payload,
+		Completed: Bool(completed),
+	}
+}
+
+func TestResourceUri(t *testing.T) {
+	Convey("Resource uris should follow the package and snake cased message name", t, func() {
+		So(ResourceUri(&Error{}), ShouldEqual, "resource:///platform/reply/error")
+		So(ResourceUri(&TraceList{}), ShouldEqual, "resource:///platform/reply/trace_list")
+		So(ResourceUri(&IpAddress{}), ShouldEqual, "resource:///platform/reply/ip_address")
+	})
+
+	Convey("Snake casing should keep acronyms together", t, func() {
+		So(snakeCase("HTTPRequest"), ShouldEqual, "http_request")
+		So(snakeCase("UserID"), ShouldEqual, "user_id")
+		So(snakeCase("foobar"), ShouldEqual, "foobar")
+	})
+}
+
+func TestCall(t *testing.T) {
+	Convey("Calling should marshal the request and decode the expected response", t, func() {
+		router := newMockRouter(newMockResourceResponse(&Trace{Name: String("response")}, true))
+
+		response := &Trace{}
+		So(Call(router, "microservice:///teltech/get/foobar", &Trace{Name: String("request")}, response), ShouldBeNil)
+		So(response.GetName(), ShouldEqual, "response")
+
+		So(len(router.requests), ShouldEqual, 1)
+		So(router.requests[0].GetRouting().GetRouteTo()[0].GetUri(), ShouldEqual, "microservice:///teltech/get/foobar")
+
+		request := &Trace{}
+		So(Unmarshal(router.requests[0].GetPayload(), request), ShouldBeNil)
+		So(request.GetName(), ShouldEqual, "request")
+	})
+
+	Convey("Calling should turn error responses into platform errors", t, func() {
+		router := newMockRouter(newErrorResponse(&Error{
+			Message: String("trace not found"),
+			Code:    Error_NOT_FOUND.Enum(),
+		}))
+
+		err := Call(router, "microservice:///teltech/get/foobar", &Trace{}, &Trace{})
+
+		var platformError *PlatformError
+		So(errors.As(err, &platformError), ShouldBeTrue)
+		So(platformError.Code, ShouldEqual, Error_NOT_FOUND)
+	})
+
+	Convey("Calling should reject responses of an unexpected resource", t, func() {
+		router := newMockRouter(newMockResourceResponse(&TraceList{}, true))
+
+		err := Call(router, "microservice:///teltech/get/foobar", &Trace{}, &Trace{})
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "resource:///platform/reply/trace_list")
+	})
+
+	Convey("Calling with an explicit response uri should expect the replies there instead", t, func() {
+		router := newMockRouter(newMockResourceResponse(&TraceList{}, true))
+
+		err := Call(router, "microservice:///teltech/get/foobar", &Trace{}, &Trace{}, WithResponseUri("resource:///platform/reply/trace_list"))
+		So(err, ShouldBeNil)
+
+		err = Call(router, "microservice:///teltech/get/foobar", &Trace{}, &Trace{}, WithResponseUri("resource:///teltech/reply/legacy_trace"))
+		So(err, ShouldNotBeNil)
+		So(err.Error(), ShouldContainSubstring, "expected resource:///teltech/reply/legacy_trace")
+	})
+}
+
+func TestCallStream(t *testing.T) {
+	Convey("Streaming should decode every response and skip heartbeats", t, func() {
+		router := newMockRouter(
+			newMockResourceResponse(&Trace{Name: String("first")}, false),
+			&Request{Routing: RouteToUri("resource:///heartbeat")},
+			newMockResourceResponse(&Trace{Name: String("second")}, true),
+		)
+
+		names := []string{}
+
+		response := &Trace{}
+		So(CallStream(router, "microservice:///teltech/list/foobar", &Trace{}, response, func() error {
+			names = append(names, response.GetName())
+
+			return nil
+		}), ShouldBeNil)
+
+		So(names, ShouldResemble, []string{"first", "second"})
+	})
+
+	Convey("Streaming should not hand the terminator of a stream that sent nothing to fn", t, func() {
+		mockResponder := newMockResponder()
+		So(NewMessageStream(mockResponder).Close(&Trace{}), ShouldBeNil)
+
+		router := newMockRouter(mockResponder.requests...)
+
+		So(CallStream(router, "microservice:///teltech/list/foobar", &Trace{}, &Trace{}, func() error {
+			t.Error("fn should not have been called")
+
+			return nil
+		}), ShouldBeNil)
+	})
+
+	Convey("Streaming should hand empty messages to fn", t, func() {
+		router := newMockRouter(newMockResourceResponse(&Trace{}, true))
+		router.responses[0].Payload = []byte{}
+
+		totalResponses := 0
+
+		So(CallStream(router, "microservice:///teltech/list/foobar", &Trace{}, &Trace{}, func() error {
+			totalResponses += 1
+
+			return nil
+		}), ShouldBeNil)
+
+		So(totalResponses, ShouldEqual, 1)
+	})
+
+	Convey("Streaming should stop on the first error response", t, func() {
+		router := newMockRouter(
+			newMockResourceResponse(&Trace{Name: String("first")}, false),
+			newErrorResponse(&Error{Message: String("failure"), Code: Error_INTERNAL.Enum()}),
+		)
+
+		totalResponses := 0
+
+		err := CallStream(router, "microservice:///teltech/list/foobar", &Trace{}, &Trace{}, func() error {
+			totalResponses += 1
+
+			return nil
+		})
+
+		var platformError *PlatformError
+		So(errors.As(err, &platformError), ShouldBeTrue)
+		So(totalResponses, ShouldEqual, 1)
+		So(router.ctx.Err(), ShouldEqual, context.Canceled)
+	})
+
+	Convey("Streaming should cancel the stream when fn returns an error", t, func() {
+		router := newMockRouter(
+			newMockResourceResponse(&Trace{Name: String("first")}, false),
+			newMockResourceResponse(&Trace{Name: String("second")}, true),
+		)
+
+		fnErr := errors.New("stop")
+
+		So(CallStream(router, "microservice:///teltech/list/foobar", &Trace{}, &Trace{}, func() error {
+			return fnErr
+		}), ShouldEqual, fnErr)
+
+		So(router.ctx.Err(), ShouldEqual, context.Canceled)
+	})
+}
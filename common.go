@@ -220,6 +220,33 @@ func RouteToUri(uri string) *Routing {
 	}
 }
 
+func IsCancellationRequest(request *Request) bool {
+	if len(request.GetRouting().GetRouteTo()) <= 0 {
+		return false
+	}
+
+	return request.GetRouting().GetRouteTo()[0].GetUri() == "resource:///platform/cancel"
+}
+
+// RequestDeadline returns the point in time after which the caller will no
+// longer be waiting for a response, if the request carries one.
+func RequestDeadline(request *Request) (time.Time, bool) {
+	if request.GetDeadline() == "" {
+		return time.Time{}, false
+	}
+
+	deadline, err := time.Parse(time.RFC3339Nano, request.GetDeadline())
+	if err != nil {
+		return time.Time{}, false
+	}
+
+	return deadline, true
+}
+
+func SetRequestDeadline(request *Request, deadline time.Time) {
+	request.Deadline = String(deadline.UTC().Format(time.RFC3339Nano))
+}
+
 func RouteToSchemeMatches(request *Request, scheme string) bool {
 	if request.Routing == nil {
 		return false
@@ -0,0 +1,112 @@
+package platform
+
+import (
+	"fmt"
+)
+
+// PlatformError is the Go representation of a resource:///platform/reply/error
+// payload, allowing callers to use errors.As instead of matching on messages.
+type PlatformError struct {
+	Code      Error_Code
+	Message   string
+	Retryable bool
+	Details   map[string]string
+	Cause     *PlatformError
+}
+
+func (e *PlatformError) Error() string {
+	if e.Cause != nil {
+		return fmt.Sprintf("%s: %s: %s", e.Code, e.Message, e.Cause)
+	}
+
+	return fmt.Sprintf("%s: %s", e.Code, e.Message)
+}
+
+func (e *PlatformError) Unwrap() error {
+	if e.Cause == nil {
+		return nil
+	}
+
+	return e.Cause
+}
+
+// Proto converts the error back into its wire representation, cause chain included.
+func (e *PlatformError) Proto() *Error {
+	if e == nil {
+		return nil
+	}
+
+	platformError := &Error{
+		Message:   String(e.Message),
+		Code:      e.Code.Enum(),
+		Retryable: Bool(e.Retryable),
+		Cause:     e.Cause.Proto(),
+	}
+
+	if len(e.Details) > 0 {
+		platformError.Details = map[string]string{}
+
+		for key, value := range e.Details {
+			platformError.Details[key] = value
+		}
+	}
+
+	return platformError
+}
+
+func NewPlatformError(code Error_Code, message string) *PlatformError {
+	return &PlatformError{
+		Code:    code,
+		Message: message,
+	}
+}
+
+func NewPlatformErrorFromProto(platformError *Error) *PlatformError {
+	if platformError == nil {
+		return nil
+	}
+
+	return &PlatformError{
+		Code:      platformError.GetCode(),
+		Message:   platformError.GetMessage(),
+		Retryable: platformError.GetRetryable(),
+		Details:   platformError.GetDetails(),
+		Cause:     NewPlatformErrorFromProto(platformError.GetCause()),
+	}
+}
+
+func IsErrorResponse(response *Request) bool {
+	if len(response.GetRouting().GetRouteTo()) <= 0 {
+		return false
+	}
+
+	return response.GetRouting().GetRouteTo()[0].GetUri() == "resource:///platform/reply/error"
+}
+
+// NewPlatformErrorFromResponse decodes the platform error carried by an error
+// response, returning nil if the response is not an error.
+func NewPlatformErrorFromResponse(response *Request) *PlatformError {
+	if !IsErrorResponse(response) {
+		return nil
+	}
+
+	platformError := &Error{}
+	if err := Unmarshal(response.GetPayload(), platformError); err != nil {
+		return &PlatformError{
+			Code:    Error_UNKNOWN,
+			Message: fmt.Sprintf("Failed to unmarshal the error response: %s", err),
+		}
+	}
+
+	return NewPlatformErrorFromProto(platformError)
+}
+
+func newErrorResponse(platformError *Error) *Request {
+	errorBytes, _ := Marshal(platformError)
+
+	return &Request{
+		Routing:   RouteToUri("resource:///platform/reply/error"),
+		Payload:   errorBytes,
+		Completed: Bool(true),
+	}
+}
@@ -0,0 +1,98 @@
+package platform
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPlatformError(t *testing.T) {
+	Convey("A platform error should survive a round trip through its proto representation", t, func() {
+		platformError := &PlatformError{
+			Code:      Error_UNAVAILABLE,
+			Message:   "the database is down",
+			Retryable: true,
+			Details: map[string]string{
+				"host": "db-1",
+			},
+			Cause: NewPlatformError(Error_INTERNAL, "connection refused"),
+		}
+
+		platformErrorBytes, err := Marshal(platformError.Proto())
+		So(err, ShouldBeNil)
+
+		decodedError := &Error{}
+		So(Unmarshal(platformErrorBytes, decodedError), ShouldBeNil)
+		So(NewPlatformErrorFromProto(decodedError), ShouldResemble, platformError)
+	})
+
+	Convey("A platform error should unwrap to its cause", t, func() {
+		cause := NewPlatformError(Error_NOT_FOUND, "user not found")
+
+		platformError := &PlatformError{
+			Code:    Error_INTERNAL,
+			Message: "failed to load the profile",
+			Cause:   cause,
+		}
+
+		So(platformError.Error(), ShouldEqual, "INTERNAL: failed to load the profile: NOT_FOUND: user not found")
+		So(errors.Unwrap(platformError), ShouldEqual, cause)
+		So(errors.Unwrap(cause), ShouldBeNil)
+	})
+
+	Convey("Only error responses should produce a platform error", t, func() {
+		So(NewPlatformErrorFromResponse(&Request{
+			Routing: RouteToUri("resource:///teltech/reply/foobar"),
+		}), ShouldBeNil)
+
+		platformError := NewPlatformErrorFromResponse(newErrorResponse(&Error{
+			Message: String("user not found"),
+			Code:    Error_NOT_FOUND.Enum(),
+		}))
+		So(platformError, ShouldNotBeNil)
+		So(platformError.Code, ShouldEqual, Error_NOT_FOUND)
+		So(platformError.Message, ShouldEqual, "user not found")
+	})
+}
+
+func TestStandardRouterPlatformError(t *testing.T) {
+	Convey("Routing to a service that replies with an error should return a platform error", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+
+		requestUuid := CreateUUID()
+
+		router := NewStandardRouterWithTopic(mockPublisher, mockSubscriber, "testing-router")
+		router.SetHeartbeatTimeout(time.Second)
+
+		go func() {
+			time.Sleep(10 * time.Millisecond)
+
+			actualUuid, _ := getStandardRouterPendingResponsesMatchingUuidPrefix(router, requestUuid)
+
+			response := newErrorResponse(&Error{
+				Message: String("user not found"),
+				Code:    Error_NOT_FOUND.Enum(),
+			})
+			response.Uuid = String(actualUuid)
+
+			responseBytes, _ := Marshal(response)
+
+			mockSubscriber.topicHandlers["testing-router"][0].HandleMessage(responseBytes)
+		}()
+
+		response, err := router.Route(&Request{
+			Uuid:    String(requestUuid),
+			Routing: RouteToUri("microservice:///teltech/get/foobar"),
+		})
+		So(response, ShouldNotBeNil)
+		So(err, ShouldNotBeNil)
+
+		var platformError *PlatformError
+		So(errors.As(err, &platformError), ShouldBeTrue)
+		So(platformError.Code, ShouldEqual, Error_NOT_FOUND)
+		So(platformError.Message, ShouldEqual, "user not found")
+	})
+}
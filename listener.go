@@ -0,0 +1,250 @@
+package platform
+
+import (
+	"container/list"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"reflect"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// ListenerMiddleware wraps the consumer handler of a listener. The topic is
+// the one the listener was added for, which may contain wildcards. Middleware
+// that returns a MetadataConsumerHandler keeps the delivery's metadata flowing
+// to the handlers it wraps.
+type ListenerMiddleware func(topic string, next ConsumerHandler) ConsumerHandler
+
+// Applies the middlewares so that the first one is the outermost
+func chainListenerMiddleware(topic string, handler ConsumerHandler, middlewares ...ListenerMiddleware) ConsumerHandler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](topic, handler)
+	}
+
+	return handler
+}
+
+type ListenerOption func(*listenerOptions)
+
+type listenerOptions struct {
+	middlewares      []ListenerMiddleware
+	subscribeOptions []SubscribeOption
+}
+
+// WithListenerMiddleware adds middleware to a single listener, it runs inside
+// of the middleware added through Service.UseListener.
+func WithListenerMiddleware(middlewares ...ListenerMiddleware) ListenerOption {
+	return func(options *listenerOptions) {
+		options.middlewares = append(options.middlewares, middlewares...)
+	}
+}
+
+// WithListenerSubscribeOptions sizes the worker pool handling the publishes of
+// a single listener.
+func WithListenerSubscribeOptions(subscribeOptions ...SubscribeOption) ListenerOption {
+	return func(options *listenerOptions) {
+		options.subscribeOptions = append(options.subscribeOptions, subscribeOptions...)
+	}
+}
+
+type EventHandler interface {
+	HandleEvent(event Message) error
+}
+
+type EventHandlerFunc func(event Message) error
+
+func (handlerFunc EventHandlerFunc) HandleEvent(event Message) error {
+	return handlerFunc(event)
+}
+
+// Creates a new, empty message of the same type as the prototype
+func newMessageLike(prototype Message) Message {
+	messageType := reflect.TypeOf(prototype)
+	if messageType.Kind() == reflect.Ptr {
+		return reflect.New(messageType.Elem()).Interface().(Message)
+	}
+
+	return reflect.New(messageType).Interface().(Message)
+}
+
+// LoggingListenerMiddleware logs every handled publish along with how long it
+// took and the error it returned, if any. The routing key tells which topic a
+// wildcard listener actually matched.
+func LoggingListenerMiddleware(topic string, next ConsumerHandler) ConsumerHandler {
+	return MetadataConsumerHandlerFunc(func(body []byte, metadata *MessageMetadata) error {
+		startedAt := time.Now()
+
+		err := HandleMessageWithMetadata(next, body, metadata)
+
+		fields := logrus.Fields{
+			"topic":    topic,
+			"size":     len(body),
+			"duration": time.Since(startedAt).String(),
+		}
+
+		if metadata.RoutingKey != "" {
+			fields["routing_key"] = metadata.RoutingKey
+		}
+
+		if metadata.MessageId != "" {
+			fields["message_id"] = metadata.MessageId
+		}
+
+		if err != nil {
+			logger.WithFields(fields).WithField("error", err.Error()).Warn("failed to handle publish")
+		} else {
+			logger.WithFields(fields).Info("handled publish")
+		}
+
+		return err
+	})
+}
+
+// MetricsListenerMiddleware reports the duration and result of every handled
+// publish.
+func MetricsListenerMiddleware(observe func(topic string, duration time.Duration, err error)) ListenerMiddleware {
+	return func(topic string, next ConsumerHandler) ConsumerHandler {
+		return MetadataConsumerHandlerFunc(func(body []byte, metadata *MessageMetadata) error {
+			startedAt := time.Now()
+
+			err := HandleMessageWithMetadata(next, body, metadata)
+
+			observe(topic, time.Since(startedAt), err)
+
+			return err
+		})
+	}
+}
+
+// DuplicateInFlight is returned for a publish while a duplicate of it is still
+// being handled, so that it goes back to the broker in case that one fails.
+var DuplicateInFlight = errors.New("a duplicate of the publish is still being handled")
+
+// DedupeListenerMiddleware drops publishes that have already been handled on
+// the same topic within the window, going by their message id or by their body
+// if they don't have one. A publish that fails or panics is forgotten so that
+// its redelivery is handled again, and its duplicates that arrive in the
+// meantime are turned down with DuplicateInFlight.
+func DedupeListenerMiddleware(window time.Duration) ListenerMiddleware {
+	deduper := &listenerDeduper{
+		window: window,
+		seen:   map[string]*list.Element{},
+		order:  list.New(),
+	}
+
+	return func(topic string, next ConsumerHandler) ConsumerHandler {
+		return MetadataConsumerHandlerFunc(func(body []byte, metadata *MessageMetadata) error {
+			key := topic + "\x00" + metadata.MessageId
+
+			if metadata.MessageId == "" {
+				hash := sha256.Sum256(append([]byte(topic+"\x00"), body...))
+				key = hex.EncodeToString(hash[:])
+			}
+
+			switch deduper.remember(key) {
+			case dedupeHandled:
+				logger.Debugf("[DedupeListenerMiddleware] %s - dropping duplicate publish", topic)
+				return nil
+
+			case dedupeInFlight:
+				logger.Debugf("[DedupeListenerMiddleware] %s - turning down a duplicate of a publish still being handled", topic)
+				return DuplicateInFlight
+			}
+
+			handled := false
+			defer func() {
+				if handled {
+					deduper.handled(key)
+				} else {
+					deduper.forget(key)
+				}
+			}()
+
+			if err := HandleMessageWithMetadata(next, body, metadata); err != nil {
+				return err
+			}
+
+			handled = true
+
+			return nil
+		})
+	}
+}
+
+type listenerDeduper struct {
+	window time.Duration
+
+	mu   sync.Mutex
+	seen map[string]*list.Element
+
+	// The seen keys from oldest to newest, so that expiring them only has to
+	// look at the front
+	order *list.List
+}
+
+type dedupeEntry struct {
+	key     string
+	seenAt  time.Time
+	handled bool
+}
+
+type dedupeState int
+
+const (
+	dedupeNew dedupeState = iota
+	dedupeInFlight
+	dedupeHandled
+)
+
+// Tells whether the key was already seen within the window, remembering it as
+// in flight if it wasn't
+func (d *listenerDeduper) remember(key string) dedupeState {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	now := time.Now()
+
+	for oldest := d.order.Front(); oldest != nil; oldest = d.order.Front() {
+		entry := oldest.Value.(*dedupeEntry)
+		if now.Sub(entry.seenAt) <= d.window {
+			break
+		}
+
+		d.order.Remove(oldest)
+		delete(d.seen, entry.key)
+	}
+
+	if element, exists := d.seen[key]; exists {
+		if element.Value.(*dedupeEntry).handled {
+			return dedupeHandled
+		}
+
+		return dedupeInFlight
+	}
+
+	d.seen[key] = d.order.PushBack(&dedupeEntry{key: key, seenAt: now})
+
+	return dedupeNew
+}
+
+func (d *listenerDeduper) handled(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if element, exists := d.seen[key]; exists {
+		element.Value.(*dedupeEntry).handled = true
+	}
+}
+
+func (d *listenerDeduper) forget(key string) {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	if element, exists := d.seen[key]; exists {
+		d.order.Remove(element)
+		delete(d.seen, key)
+	}
+}
@@ -0,0 +1,230 @@
+package platform
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestServiceEventListener(t *testing.T) {
+	Convey("An event listener should receive decoded events", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+
+		service, err := NewServiceWithResponder("test-service", mockPublisher, mockSubscriber, nil, newMockResponder())
+		So(err, ShouldBeNil)
+
+		events := []*Route{}
+
+		service.AddEventListener("testing", &Route{}, EventHandlerFunc(func(event Message) error {
+			events = append(events, event.(*Route))
+
+			return nil
+		}))
+
+		firstBytes, _ := Marshal(&Route{Uri: String("first")})
+		secondBytes, _ := Marshal(&Route{Uri: String("second")})
+
+		So(mockSubscriber.topicHandlers["testing"][0].HandleMessage(firstBytes), ShouldBeNil)
+		So(mockSubscriber.topicHandlers["testing"][0].HandleMessage(secondBytes), ShouldBeNil)
+
+		So(events, ShouldHaveLength, 2)
+		So(events[0].GetUri(), ShouldEqual, "first")
+		So(events[1].GetUri(), ShouldEqual, "second")
+		So(len(mockPublisher.mockPublishes), ShouldEqual, 0)
+	})
+
+	Convey("An undecodable event should be dead lettered", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+
+		service, err := NewServiceWithResponder("test-service", mockPublisher, mockSubscriber, nil, newMockResponder())
+		So(err, ShouldBeNil)
+
+		totalEvents := 0
+
+		service.AddEventListener("testing", &Route{}, EventHandlerFunc(func(event Message) error {
+			totalEvents += 1
+
+			return nil
+		}))
+
+		So(mockSubscriber.topicHandlers["testing"][0].HandleMessage([]byte{0xff, 0xff}), ShouldBeNil)
+
+		So(totalEvents, ShouldEqual, 0)
+		So(len(mockPublisher.mockPublishes), ShouldEqual, 1)
+		So(mockPublisher.mockPublishes[0].topic, ShouldEqual, "deadletter.listener.testing")
+		So(mockPublisher.mockPublishes[0].body, ShouldResemble, []byte{0xff, 0xff})
+	})
+}
+
+func TestServiceListenerMiddleware(t *testing.T) {
+	Convey("Service listener middleware should run before listener middleware", t, func() {
+		mockSubscriber := newMockSubscriber()
+
+		service, err := NewServiceWithResponder("test-service", newMockPublisher(), mockSubscriber, nil, newMockResponder())
+		So(err, ShouldBeNil)
+
+		calls := []string{}
+
+		recordingMiddleware := func(name string) ListenerMiddleware {
+			return func(topic string, next ConsumerHandler) ConsumerHandler {
+				return ConsumerHandlerFunc(func(body []byte) error {
+					calls = append(calls, name+":"+topic)
+
+					return next.HandleMessage(body)
+				})
+			}
+		}
+
+		service.UseListener(recordingMiddleware("first"), LoggingListenerMiddleware)
+		service.AddListener("testing", ConsumerHandlerFunc(func(body []byte) error {
+			calls = append(calls, "listener")
+
+			return nil
+		}), WithListenerMiddleware(recordingMiddleware("second")))
+
+		So(mockSubscriber.topicHandlers["testing"][0].HandleMessage([]byte{}), ShouldBeNil)
+		So(calls, ShouldResemble, []string{"first:testing", "second:testing", "listener"})
+	})
+
+	Convey("Listeners should be handed the delivery's metadata through the built-in middleware", t, func() {
+		mockSubscriber := newMockSubscriber()
+
+		service, err := NewServiceWithResponder("test-service", newMockPublisher(), mockSubscriber, nil, newMockResponder())
+		So(err, ShouldBeNil)
+
+		var received *MessageMetadata
+
+		service.UseListener(LoggingListenerMiddleware, DedupeListenerMiddleware(time.Minute))
+		service.AddListener("events.*", MetadataConsumerHandlerFunc(func(body []byte, metadata *MessageMetadata) error {
+			received = metadata
+
+			return nil
+		}))
+
+		metadata := &MessageMetadata{
+			MessageId:  "message-id",
+			RoutingKey: "events.created",
+		}
+
+		So(HandleMessageWithMetadata(mockSubscriber.topicHandlers["events.*"][0], []byte{}, metadata), ShouldBeNil)
+		So(received, ShouldEqual, metadata)
+	})
+}
+
+func TestBuiltinListenerMiddleware(t *testing.T) {
+	Convey("The dedupe middleware should drop repeated publishes within the window", t, func() {
+		totalCalls := 0
+		failNext := false
+
+		handler := DedupeListenerMiddleware(50*time.Millisecond)("testing", ConsumerHandlerFunc(func(body []byte) error {
+			totalCalls += 1
+
+			if failNext {
+				failNext = false
+				return errors.New("failed")
+			}
+
+			return nil
+		}))
+
+		So(handler.HandleMessage([]byte("a")), ShouldBeNil)
+		So(handler.HandleMessage([]byte("a")), ShouldBeNil)
+		So(handler.HandleMessage([]byte("b")), ShouldBeNil)
+		So(totalCalls, ShouldEqual, 2)
+
+		time.Sleep(75 * time.Millisecond)
+
+		So(handler.HandleMessage([]byte("a")), ShouldBeNil)
+		So(totalCalls, ShouldEqual, 3)
+
+		failNext = true
+		So(handler.HandleMessage([]byte("c")), ShouldNotBeNil)
+		So(handler.HandleMessage([]byte("c")), ShouldBeNil)
+		So(totalCalls, ShouldEqual, 5)
+	})
+
+	Convey("The dedupe middleware should go by the message id when there is one", t, func() {
+		totalCalls := 0
+
+		handler := DedupeListenerMiddleware(time.Minute)("testing", ConsumerHandlerFunc(func(body []byte) error {
+			totalCalls += 1
+			return nil
+		}))
+
+		So(HandleMessageWithMetadata(handler, []byte("a"), &MessageMetadata{MessageId: "1"}), ShouldBeNil)
+		So(HandleMessageWithMetadata(handler, []byte("b"), &MessageMetadata{MessageId: "1"}), ShouldBeNil)
+		So(HandleMessageWithMetadata(handler, []byte("a"), &MessageMetadata{MessageId: "2"}), ShouldBeNil)
+		So(totalCalls, ShouldEqual, 2)
+	})
+
+	Convey("The dedupe middleware should forget a publish whose listener panicked", t, func() {
+		totalCalls := 0
+
+		handler := DedupeListenerMiddleware(time.Minute)("testing", ConsumerHandlerFunc(func(body []byte) error {
+			totalCalls += 1
+
+			if totalCalls == 1 {
+				panic("failed")
+			}
+
+			return nil
+		}))
+
+		So(func() { handler.HandleMessage([]byte("a")) }, ShouldPanic)
+		So(handler.HandleMessage([]byte("a")), ShouldBeNil)
+		So(handler.HandleMessage([]byte("a")), ShouldBeNil)
+		So(totalCalls, ShouldEqual, 2)
+	})
+
+	Convey("The dedupe middleware should turn down duplicates of a publish that is still being handled", t, func() {
+		started := make(chan bool)
+		finish := make(chan error)
+
+		handler := DedupeListenerMiddleware(time.Minute)("testing", ConsumerHandlerFunc(func(body []byte) error {
+			started <- true
+			return <-finish
+		}))
+
+		results := make(chan error)
+		go func() {
+			results <- handler.HandleMessage([]byte("a"))
+		}()
+
+		<-started
+		So(handler.HandleMessage([]byte("a")), ShouldEqual, DuplicateInFlight)
+
+		finish <- errors.New("failed")
+		So(<-results, ShouldNotBeNil)
+
+		// The failed publish was forgotten, so its redelivery is handled again
+		go func() {
+			results <- handler.HandleMessage([]byte("a"))
+		}()
+
+		<-started
+		finish <- nil
+		So(<-results, ShouldBeNil)
+
+		So(handler.HandleMessage([]byte("a")), ShouldBeNil)
+	})
+
+	Convey("The metrics middleware should observe the result of every publish", t, func() {
+		observedTopic := ""
+		var observedErr error
+
+		handler := MetricsListenerMiddleware(func(topic string, duration time.Duration, err error) {
+			observedTopic = topic
+			observedErr = err
+		})("testing", ConsumerHandlerFunc(func(body []byte) error {
+			return errors.New("failed")
+		}))
+
+		So(handler.HandleMessage([]byte{}), ShouldNotBeNil)
+		So(observedTopic, ShouldEqual, "testing")
+		So(observedErr, ShouldResemble, errors.New("failed"))
+	})
+}
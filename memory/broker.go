@@ -0,0 +1,204 @@
+// Package memory implements the platform publisher and subscriber on top of
+// an in-process topic exchange, so that services, routers and tracers can run
+// together in a single process without a broker.
+package memory
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/microplatform-io/platform"
+)
+
+type delivery struct {
+	messageId   string
+	routingKey  string
+	body        []byte
+	publishedAt time.Time
+}
+
+func (d delivery) metadata() *platform.MessageMetadata {
+	return &platform.MessageMetadata{
+		MessageId:  d.messageId,
+		RoutingKey: d.routingKey,
+		Headers:    map[string]interface{}{},
+		Timestamp:  d.publishedAt,
+	}
+}
+
+// Broker is an in-process topic exchange routing every publish to the queues
+// that have a matching binding, using the same wildcards as AMQP.
+type Broker struct {
+	mu     sync.Mutex
+	queues map[string]*queue
+}
+
+func (b *Broker) publish(routingKey string, body []byte) {
+	// The publisher is free to reuse the body once the publish returns
+	bodyCopy := make([]byte, len(body))
+	copy(bodyCopy, body)
+
+	messageId, publishedAt := platform.CreateUUID(), time.Now()
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	routed := false
+
+	for _, queue := range b.queues {
+		if queue.matches(routingKey) {
+			queue.push(delivery{
+				messageId:   messageId,
+				routingKey:  routingKey,
+				body:        bodyCopy,
+				publishedAt: publishedAt,
+			})
+
+			routed = true
+		}
+	}
+
+	if !routed {
+		logger.Debugf("[Broker.publish] %s - no queue is bound to the routing key, dropping the publish", routingKey)
+	}
+}
+
+func (b *Broker) declareQueue(name string, exclusive, autoDelete bool) (*queue, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if name == "" {
+		name = "gen-" + platform.CreateUUID()
+	}
+
+	if existingQueue, exists := b.queues[name]; exists {
+		if existingQueue.exclusive || exclusive {
+			return nil, fmt.Errorf("queue %s is exclusive to another subscriber", name)
+		}
+
+		existingQueue.consumers++
+
+		return existingQueue, nil
+	}
+
+	queue := newQueue(name, exclusive, autoDelete)
+	queue.consumers++
+
+	b.queues[name] = queue
+
+	return queue, nil
+}
+
+func (b *Broker) bindQueue(queue *queue, topic string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	queue.bindings[topic]++
+}
+
+// The binding stays in place for as long as another subscription of the queue
+// still needs it
+func (b *Broker) unbindQueue(queue *queue, topic string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	queue.bindings[topic]--
+
+	if queue.bindings[topic] <= 0 {
+		delete(queue.bindings, topic)
+	}
+}
+
+// Exclusive queues go away with their subscriber, auto delete queues once
+// their last subscriber is gone and every other queue keeps its messages.
+func (b *Broker) releaseQueue(queue *queue) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	queue.consumers--
+
+	if queue.exclusive || (queue.autoDelete && queue.consumers <= 0) {
+		delete(b.queues, queue.name)
+	}
+}
+
+func NewBroker() *Broker {
+	return &Broker{
+		queues: map[string]*queue{},
+	}
+}
+
+type queue struct {
+	name       string
+	exclusive  bool
+	autoDelete bool
+	consumers  int
+
+	// How many subscriptions bound each topic, guarded by the broker's lock
+	bindings map[string]int
+
+	mu         sync.Mutex
+	cond       *sync.Cond
+	deliveries []delivery
+}
+
+func (q *queue) matches(routingKey string) bool {
+	for topic := range q.bindings {
+		if platform.TopicMatches(topic, routingKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+func (q *queue) push(d delivery) {
+	q.mu.Lock()
+	q.deliveries = append(q.deliveries, d)
+	q.mu.Unlock()
+
+	q.cond.Signal()
+}
+
+// Blocks until a delivery is available or quit is closed. Consumers of the
+// same queue compete for its deliveries.
+func (q *queue) pop(quit chan interface{}) (delivery, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	for len(q.deliveries) <= 0 {
+		select {
+		case <-quit:
+			return delivery{}, false
+		default:
+		}
+
+		q.cond.Wait()
+	}
+
+	d := q.deliveries[0]
+	q.deliveries = q.deliveries[1:]
+
+	return d, true
+}
+
+// Wakes every consumer up so that they can notice they have been closed
+func (q *queue) wake() {
+	q.mu.Lock()
+	q.cond.Broadcast()
+	q.mu.Unlock()
+}
+
+func newQueue(name string, exclusive, autoDelete bool) *queue {
+	q := &queue{
+		name:       name,
+		exclusive:  exclusive,
+		autoDelete: autoDelete,
+		bindings:   map[string]int{},
+	}
+
+	q.cond = sync.NewCond(&q.mu)
+
+	return q
+}
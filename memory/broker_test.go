@@ -0,0 +1,481 @@
+package memory
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/microplatform-io/platform"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+type recordingHandler struct {
+	mu     sync.Mutex
+	bodies []string
+}
+
+func (h *recordingHandler) HandleMessage(body []byte) error {
+	h.mu.Lock()
+	h.bodies = append(h.bodies, string(body))
+	h.mu.Unlock()
+
+	return nil
+}
+
+func (h *recordingHandler) total() int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	return len(h.bodies)
+}
+
+func waitFor(condition func() bool) bool {
+	for deadline := time.Now().Add(time.Second); time.Now().Before(deadline); time.Sleep(time.Millisecond) {
+		if condition() {
+			return true
+		}
+	}
+
+	return false
+}
+
+func TestBroker(t *testing.T) {
+	Convey("Publishes should be routed to the subscriptions with a matching topic", t, func() {
+		broker := NewBroker()
+		publisher := NewPublisher(broker)
+
+		subscriber, err := NewSubscriber(broker, "testing")
+		So(err, ShouldBeNil)
+		defer subscriber.Close(context.Background())
+
+		created := &recordingHandler{}
+		everything := &recordingHandler{}
+
+		subscriber.Subscribe("events.#.created", created)
+		subscriber.Subscribe("events.*", everything)
+		subscriber.Run()
+
+		So(publisher.Publish("events.user.account.created", []byte("account")), ShouldBeNil)
+		So(publisher.Publish("events.deleted", []byte("deleted")), ShouldBeNil)
+		So(publisher.Publish("unrelated", []byte("unrelated")), ShouldBeNil)
+
+		So(waitFor(func() bool { return created.total() == 1 && everything.total() == 1 }), ShouldBeTrue)
+		So(created.bodies, ShouldResemble, []string{"account"})
+		So(everything.bodies, ShouldResemble, []string{"deleted"})
+	})
+
+	Convey("Metadata consumer handlers should be told which routing key a publish matched", t, func() {
+		broker := NewBroker()
+		publisher := NewPublisher(broker)
+
+		subscriber, err := NewSubscriber(broker, "testing")
+		So(err, ShouldBeNil)
+		defer subscriber.Close(context.Background())
+
+		received := make(chan *platform.MessageMetadata, 1)
+
+		subscriber.Subscribe("events.*", platform.MetadataConsumerHandlerFunc(func(body []byte, metadata *platform.MessageMetadata) error {
+			received <- metadata
+			return nil
+		}))
+		subscriber.Run()
+
+		So(publisher.Publish("events.created", []byte("created")), ShouldBeNil)
+
+		select {
+		case metadata := <-received:
+			So(metadata.RoutingKey, ShouldEqual, "events.created")
+			So(metadata.MessageId, ShouldNotBeEmpty)
+			So(metadata.Timestamp.IsZero(), ShouldBeFalse)
+
+		case <-time.After(time.Second):
+			t.Fatal("the publish was never handled")
+		}
+	})
+
+	Convey("A subscription should handle no more publishes at a time than its concurrency", t, func() {
+		broker := NewBroker()
+		publisher := NewPublisher(broker)
+
+		subscriber, err := NewSubscriber(broker, "testing")
+		So(err, ShouldBeNil)
+		defer subscriber.Close(context.Background())
+
+		release := make(chan struct{})
+		handler := &recordingHandler{}
+
+		subscriber.Subscribe("testing", platform.ConsumerHandlerFunc(func(body []byte) error {
+			<-release
+			return handler.HandleMessage(body)
+		}), platform.WithConcurrency(1))
+		subscriber.Run()
+
+		So(publisher.Publish("testing", []byte("first")), ShouldBeNil)
+		So(publisher.Publish("testing", []byte("second")), ShouldBeNil)
+
+		So(waitFor(func() bool { return subscriber.WorkerPools()[0].Busy == 1 }), ShouldBeTrue)
+		So(subscriber.WorkerPools(), ShouldResemble, []platform.WorkerPool{
+			platform.WorkerPool{Topic: "testing", Size: 1, Busy: 1, MaxSize: 1},
+		})
+
+		close(release)
+
+		So(waitFor(func() bool { return handler.total() == 2 }), ShouldBeTrue)
+		So(handler.bodies, ShouldResemble, []string{"first", "second"})
+	})
+
+	Convey("An ordered subscription should handle the publishes of a key in order", t, func() {
+		broker := NewBroker()
+		publisher := NewPublisher(broker)
+
+		subscriber, err := NewSubscriber(broker, "testing")
+		So(err, ShouldBeNil)
+		defer subscriber.Close(context.Background())
+
+		handler := &recordingHandler{}
+		byAccount := func(body []byte, metadata *platform.MessageMetadata) string {
+			return strings.Split(string(body), ":")[0]
+		}
+
+		subscriber.Subscribe("testing", platform.ConsumerHandlerFunc(func(body []byte) error {
+			// The first publishes take the longest, they'd be overtaken if they weren't ordered
+			sequence, _ := strconv.Atoi(strings.Split(string(body), ":")[1])
+			time.Sleep(time.Duration(10-sequence) * time.Millisecond)
+
+			return handler.HandleMessage(body)
+		}), platform.WithOrdering(4, byAccount))
+		subscriber.Run()
+
+		expected := []string{}
+		for i := 0; i < 10; i++ {
+			So(publisher.Publish("testing", []byte("first:"+strconv.Itoa(i))), ShouldBeNil)
+			expected = append(expected, "first:"+strconv.Itoa(i))
+		}
+
+		So(waitFor(func() bool { return handler.total() == 10 }), ShouldBeTrue)
+		So(handler.bodies, ShouldResemble, expected)
+	})
+
+	Convey("Subscribers of the same queue should compete for its publishes", t, func() {
+		broker := NewBroker()
+		publisher := NewPublisher(broker)
+
+		firstHandler := &recordingHandler{}
+		secondHandler := &recordingHandler{}
+
+		for _, handler := range []*recordingHandler{firstHandler, secondHandler} {
+			subscriber, err := NewSubscriber(broker, "testing")
+			So(err, ShouldBeNil)
+			defer subscriber.Close(context.Background())
+
+			subscriber.Subscribe("testing", handler)
+			subscriber.Run()
+		}
+
+		for i := 0; i < 100; i++ {
+			So(publisher.Publish("testing", []byte("body")), ShouldBeNil)
+		}
+
+		So(waitFor(func() bool { return firstHandler.total()+secondHandler.total() == 100 }), ShouldBeTrue)
+	})
+
+	Convey("Publishes should be queued until a subscriber runs", t, func() {
+		broker := NewBroker()
+		publisher := NewPublisher(broker)
+
+		subscriber, err := NewSubscriber(broker, "testing")
+		So(err, ShouldBeNil)
+		defer subscriber.Close(context.Background())
+
+		handler := &recordingHandler{}
+		subscriber.Subscribe("testing", handler)
+
+		So(publisher.Publish("testing", []byte("body")), ShouldBeNil)
+		So(handler.total(), ShouldEqual, 0)
+
+		subscriber.Run()
+		So(waitFor(func() bool { return handler.total() == 1 }), ShouldBeTrue)
+	})
+
+	Convey("Unsubscribing should stop the deliveries and wait for the handlers still running", t, func() {
+		broker := NewBroker()
+		publisher := NewPublisher(broker)
+
+		subscriber, err := NewSubscriber(broker, "testing")
+		So(err, ShouldBeNil)
+		defer subscriber.Close(context.Background())
+
+		handling := make(chan bool)
+		release := make(chan bool)
+		finished := false
+
+		subscriber.Subscribe("testing", platform.ConsumerHandlerFunc(func(body []byte) error {
+			handling <- true
+			<-release
+			finished = true
+
+			return nil
+		}))
+		subscriber.Run()
+
+		So(publisher.Publish("testing", []byte("body")), ShouldBeNil)
+		<-handling
+
+		unsubscribed := make(chan bool)
+		go func() {
+			subscriber.Unsubscribe("testing")
+			close(unsubscribed)
+		}()
+
+		select {
+		case <-unsubscribed:
+			t.Fatal("unsubscribe returned while the handler was still running")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		close(release)
+		<-unsubscribed
+		So(finished, ShouldBeTrue)
+
+		// Nothing is bound to the topic anymore
+		So(publisher.Publish("testing", []byte("body")), ShouldBeNil)
+		So(subscriber.queue.matches("testing"), ShouldBeFalse)
+	})
+
+	Convey("Unsubscribing from a shared queue should leave the bindings of the other subscribers in place", t, func() {
+		broker := NewBroker()
+		publisher := NewPublisher(broker)
+
+		firstSubscriber, err := NewSubscriber(broker, "testing")
+		So(err, ShouldBeNil)
+		defer firstSubscriber.Close(context.Background())
+
+		secondSubscriber, err := NewSubscriber(broker, "testing")
+		So(err, ShouldBeNil)
+		defer secondSubscriber.Close(context.Background())
+
+		firstSubscriber.Subscribe("testing", &recordingHandler{})
+
+		secondHandler := &recordingHandler{}
+		secondSubscriber.Subscribe("testing", secondHandler)
+		secondSubscriber.Run()
+
+		firstSubscriber.Unsubscribe("testing")
+		So(secondSubscriber.queue.matches("testing"), ShouldBeTrue)
+
+		So(publisher.Publish("testing", []byte("body")), ShouldBeNil)
+		So(waitFor(func() bool { return secondHandler.total() == 1 }), ShouldBeTrue)
+
+		secondSubscriber.Unsubscribe("testing")
+		So(secondSubscriber.queue.matches("testing"), ShouldBeFalse)
+	})
+
+	Convey("Closing should wait for the running handlers until the context is done", t, func() {
+		broker := NewBroker()
+		publisher := NewPublisher(broker)
+
+		subscriber, err := NewSubscriber(broker, "testing")
+		So(err, ShouldBeNil)
+
+		var drainingSubscriber platform.DrainingSubscriber = subscriber
+
+		handling := make(chan bool)
+		release := make(chan bool)
+
+		subscriber.Subscribe("testing", platform.ConsumerHandlerFunc(func(body []byte) error {
+			handling <- true
+			<-release
+
+			return nil
+		}))
+		subscriber.Run()
+
+		So(publisher.Publish("testing", []byte("first")), ShouldBeNil)
+		<-handling
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+
+		So(drainingSubscriber.Close(ctx), ShouldResemble, context.DeadlineExceeded)
+		So(drainingSubscriber.Close(context.Background()), ShouldBeNil)
+
+		close(release)
+	})
+
+	Convey("Exclusive queues should not be shared and should go away with their subscriber", t, func() {
+		broker := NewBroker()
+
+		subscriber, err := NewExclusiveSubscriber(broker, "exclusive")
+		So(err, ShouldBeNil)
+
+		_, err = NewSubscriber(broker, "exclusive")
+		So(err, ShouldNotBeNil)
+
+		So(subscriber.Close(context.Background()), ShouldBeNil)
+
+		subscriber, err = NewSubscriber(broker, "exclusive")
+		So(err, ShouldBeNil)
+		So(subscriber.Close(context.Background()), ShouldBeNil)
+	})
+}
+
+func TestServiceAndRouter(t *testing.T) {
+	Convey("A router should be able to call a service through the broker", t, func() {
+		broker := NewBroker()
+		publisher := NewPublisher(broker)
+
+		serviceSubscriber, err := NewSubscriber(broker, "test-service")
+		So(err, ShouldBeNil)
+		defer serviceSubscriber.Close(context.Background())
+
+		service, err := platform.NewService("test-service", publisher, serviceSubscriber, nil)
+		So(err, ShouldBeNil)
+
+		service.AddHandler("/teltech/get/foobar", platform.HandlerFunc(func(responder platform.Responder, request *platform.Request) {
+			platform.RespondWithMessage(responder, &platform.Route{
+				Uri: platform.String("foobar"),
+			}, true)
+		}))
+		serviceSubscriber.Run()
+
+		So(serviceSubscriber.WorkerPools(), ShouldHaveLength, 1)
+
+		routerSubscriber, err := NewExclusiveSubscriber(broker, "")
+		So(err, ShouldBeNil)
+		defer routerSubscriber.Close(context.Background())
+
+		router := platform.NewStandardRouter(publisher, routerSubscriber)
+
+		response := &platform.Route{}
+		So(platform.Call(router, "microservice:///teltech/get/foobar", &platform.Route{}, response), ShouldBeNil)
+		So(response.GetUri(), ShouldEqual, "foobar")
+	})
+
+	Convey("A router should be able to call a templated handler through the broker on its default settings", t, func() {
+		broker := NewBroker()
+		publisher := NewPublisher(broker)
+
+		serviceSubscriber, err := NewSubscriber(broker, "test-service")
+		So(err, ShouldBeNil)
+		defer serviceSubscriber.Close(context.Background())
+
+		service, err := platform.NewService("test-service", publisher, serviceSubscriber, nil)
+		So(err, ShouldBeNil)
+
+		service.AddHandler("/teltech/get/{name}", platform.HandlerFunc(func(responder platform.Responder, request *platform.Request) {
+			platform.RespondWithMessage(responder, &platform.Route{
+				Uri: platform.String(platform.PathParam(request, "name")),
+			}, true)
+		}))
+		serviceSubscriber.Run()
+
+		routerSubscriber, err := NewExclusiveSubscriber(broker, "")
+		So(err, ShouldBeNil)
+		defer routerSubscriber.Close(context.Background())
+
+		router := platform.NewStandardRouter(publisher, routerSubscriber)
+
+		response := &platform.Route{}
+		So(platform.Call(router, "microservice:///teltech/get/foobar", &platform.Route{}, response), ShouldBeNil)
+		So(response.GetUri(), ShouldEqual, "foobar")
+	})
+
+	Convey("A router publishing legacy routing keys should still reach exact paths", t, func() {
+		broker := NewBroker()
+		publisher := NewPublisher(broker)
+
+		serviceSubscriber, err := NewSubscriber(broker, "test-service")
+		So(err, ShouldBeNil)
+		defer serviceSubscriber.Close(context.Background())
+
+		service, err := platform.NewService("test-service", publisher, serviceSubscriber, nil)
+		So(err, ShouldBeNil)
+
+		service.AddHandler("/teltech/get/foobar", platform.HandlerFunc(func(responder platform.Responder, request *platform.Request) {
+			platform.RespondWithMessage(responder, &platform.Route{
+				Uri: platform.String("foobar"),
+			}, true)
+		}))
+		serviceSubscriber.Run()
+
+		routerSubscriber, err := NewExclusiveSubscriber(broker, "")
+		So(err, ShouldBeNil)
+		defer routerSubscriber.Close(context.Background())
+
+		router := platform.NewStandardRouter(publisher, routerSubscriber)
+		router.SetLegacyRoutingKeys(true)
+
+		response := &platform.Route{}
+		So(platform.Call(router, "microservice:///teltech/get/foobar", &platform.Route{}, response), ShouldBeNil)
+		So(response.GetUri(), ShouldEqual, "foobar")
+	})
+}
+
+func TestServiceCancellation(t *testing.T) {
+	Convey("Every instance of a service should get the cancellations, so that the one handling the request stops", t, func() {
+		broker := NewBroker()
+		publisher := NewPublisher(broker)
+
+		// Hands over where the handler reports whether it was cancelled
+		started := make(chan chan bool)
+
+		for instance := 0; instance < 2; instance++ {
+			serviceSubscriber, err := NewSubscriber(broker, "test-service")
+			So(err, ShouldBeNil)
+			defer serviceSubscriber.Close(context.Background())
+
+			cancellationSubscriber, err := NewExclusiveSubscriber(broker, "")
+			So(err, ShouldBeNil)
+			defer cancellationSubscriber.Close(context.Background())
+
+			service, err := platform.NewService("test-service", publisher, serviceSubscriber, nil)
+			So(err, ShouldBeNil)
+
+			service.SetCancellationSubscriber(cancellationSubscriber)
+
+			service.AddHandler("/teltech/get/report", platform.HandlerFunc(func(responder platform.Responder, request *platform.Request) {
+				cancelled := make(chan bool, 1)
+				started <- cancelled
+
+				select {
+				case <-platform.ResponderContext(responder).Done():
+					cancelled <- true
+				case <-time.After(time.Second):
+					cancelled <- false
+				}
+			}))
+
+			cancellationSubscriber.Run()
+			serviceSubscriber.Run()
+		}
+
+		routerSubscriber, err := NewExclusiveSubscriber(broker, "")
+		So(err, ShouldBeNil)
+		defer routerSubscriber.Close(context.Background())
+
+		router := platform.NewStandardRouter(publisher, routerSubscriber)
+
+		// Whichever instance picks a request up has to notice its cancellation
+		for i := 0; i < 6; i++ {
+			ctx, cancel := context.WithCancel(context.Background())
+
+			routed := make(chan error, 1)
+			go func() {
+				_, err := router.RouteContext(ctx, &platform.Request{
+					Routing: platform.RouteToUri("microservice:///teltech/get/report"),
+				})
+
+				routed <- err
+			}()
+
+			cancelled := <-started
+			cancel()
+
+			So(<-routed, ShouldResemble, context.Canceled)
+			So(<-cancelled, ShouldBeTrue)
+		}
+	})
+}
@@ -0,0 +1,7 @@
+package memory
+
+import "github.com/microplatform-io/platform"
+
+var (
+	logger = platform.GetLogger("platform.memory")
+)
@@ -0,0 +1,17 @@
+package memory
+
+type Publisher struct {
+	broker *Broker
+}
+
+func (p *Publisher) Publish(topic string, body []byte) error {
+	p.broker.publish(topic, body)
+
+	return nil
+}
+
+func NewPublisher(broker *Broker) *Publisher {
+	return &Publisher{
+		broker: broker,
+	}
+}
@@ -0,0 +1,365 @@
+package memory
+
+import (
+	"context"
+	"sync"
+
+	"github.com/microplatform-io/platform"
+)
+
+// The number of deliveries a subscription handles at the same time
+const DEFAULT_CONCURRENCY = 50
+
+type subscription struct {
+	topic   string
+	handler platform.ConsumerHandler
+
+	// Every topic bound for the subscription, its own one included
+	topics []string
+
+	// Deliveries are handled by goroutines started on demand, up to the
+	// capacity of workers. An adaptive pool is simply bounded by its max.
+	workers chan struct{}
+	minSize int
+
+	// An ordered subscription hands its deliveries to the lane their ordering
+	// key picks, the workers then only bound how many can be queued up
+	ordering *platform.SubscribeOptions
+	lanes    []*lane
+
+	// The deliveries being handled, guarded by the subscriber's lock along with
+	// unsubscribed so that nothing new starts once it's being waited on
+	inflight     sync.WaitGroup
+	unsubscribed bool
+}
+
+func (s *subscription) workerPool() platform.WorkerPool {
+	if len(s.lanes) > 0 {
+		running := 0
+		for _, lane := range s.lanes {
+			if lane.isRunning() {
+				running++
+			}
+		}
+
+		return platform.WorkerPool{
+			Topic:   s.topic,
+			Size:    running,
+			Busy:    running,
+			MinSize: len(s.lanes),
+			MaxSize: len(s.lanes),
+		}
+	}
+
+	busy := len(s.workers)
+
+	return platform.WorkerPool{
+		Topic:   s.topic,
+		Size:    busy,
+		Busy:    busy,
+		MinSize: s.minSize,
+		MaxSize: cap(s.workers),
+	}
+}
+
+func (s *subscription) canHandle(d delivery) bool {
+	if s.topic == "" {
+		return true
+	}
+
+	for _, topic := range s.topics {
+		if platform.TopicMatches(topic, d.routingKey) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// Handles what is pushed onto it one at a time and in order, a goroutine only
+// runs while there is something to handle
+type lane struct {
+	mu      sync.Mutex
+	pending []func()
+	running bool
+}
+
+func (l *lane) push(handle func()) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	l.pending = append(l.pending, handle)
+
+	if !l.running {
+		l.running = true
+		go l.run()
+	}
+}
+
+func (l *lane) run() {
+	for {
+		l.mu.Lock()
+		if len(l.pending) <= 0 {
+			l.running = false
+			l.mu.Unlock()
+			return
+		}
+
+		handle := l.pending[0]
+		l.pending = l.pending[1:]
+		l.mu.Unlock()
+
+		handle()
+	}
+}
+
+func (l *lane) isRunning() bool {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	return l.running
+}
+
+type Subscriber struct {
+	broker *Broker
+	queue  *queue
+
+	mu            sync.Mutex
+	subscriptions []*subscription
+	running       bool
+	closed        bool
+
+	wg   sync.WaitGroup
+	quit chan interface{}
+}
+
+func (s *Subscriber) Subscribe(topic string, handler platform.ConsumerHandler, options ...platform.SubscribeOption) {
+	subscribeOptions := platform.NewSubscribeOptions(options...)
+
+	concurrency, minSize := subscribeOptions.Concurrency, 0
+	if subscribeOptions.Adaptive {
+		concurrency, minSize = subscribeOptions.MaxConcurrency, subscribeOptions.MinConcurrency
+	}
+
+	if concurrency <= 0 {
+		concurrency = DEFAULT_CONCURRENCY
+	}
+
+	subscription := &subscription{
+		topic:   topic,
+		handler: handler,
+		topics:  subscribeOptions.Topics(topic),
+		workers: make(chan struct{}, concurrency),
+		minSize: minSize,
+	}
+
+	if subscribeOptions.Ordered() {
+		subscription.ordering = subscribeOptions
+		subscription.lanes = make([]*lane, subscribeOptions.Lanes)
+
+		for i := range subscription.lanes {
+			subscription.lanes[i] = &lane{}
+		}
+	}
+
+	s.mu.Lock()
+	s.subscriptions = append(s.subscriptions, subscription)
+	s.mu.Unlock()
+
+	for _, topic := range subscription.topics {
+		s.broker.bindQueue(s.queue, topic)
+	}
+}
+
+// Unsubscribe unbinds the topic, along with the ones bound for its
+// subscriptions, from the queue and waits for the deliveries its handlers are
+// still working on. Bindings that other subscribers of a shared queue made for
+// the same topics are left in place.
+func (s *Subscriber) Unsubscribe(topic string) {
+	s.mu.Lock()
+	subscriptions := []*subscription{}
+	unsubscribed := []*subscription{}
+
+	for _, subscription := range s.subscriptions {
+		if subscription.topic == topic {
+			subscription.unsubscribed = true
+			unsubscribed = append(unsubscribed, subscription)
+		} else {
+			subscriptions = append(subscriptions, subscription)
+		}
+	}
+
+	s.subscriptions = subscriptions
+	s.mu.Unlock()
+
+	if len(unsubscribed) <= 0 {
+		return
+	}
+
+	for _, subscription := range unsubscribed {
+		for _, topic := range subscription.topics {
+			s.broker.unbindQueue(s.queue, topic)
+		}
+	}
+
+	for _, subscription := range unsubscribed {
+		subscription.inflight.Wait()
+	}
+}
+
+func (s *Subscriber) WorkerPools() []platform.WorkerPool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	workerPools := []platform.WorkerPool{}
+	for _, subscription := range s.subscriptions {
+		workerPools = append(workerPools, subscription.workerPool())
+	}
+
+	return workerPools
+}
+
+// Run starts consuming the queue in the background, much like the AMQP
+// subscriber it returns once the consumer is in place.
+func (s *Subscriber) Run() {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.running || s.closed {
+		return
+	}
+
+	s.running = true
+	s.wg.Add(1)
+
+	go s.consume()
+}
+
+func (s *Subscriber) consume() {
+	defer s.wg.Done()
+
+	for {
+		d, ok := s.queue.pop(s.quit)
+		if !ok {
+			return
+		}
+
+		s.dispatch(d)
+	}
+}
+
+func (s *Subscriber) dispatch(d delivery) {
+	s.mu.Lock()
+	subscriptions := append([]*subscription{}, s.subscriptions...)
+	s.mu.Unlock()
+
+	wasHandled := false
+
+	for _, subscription := range subscriptions {
+		if !subscription.canHandle(d) {
+			continue
+		}
+
+		wasHandled = true
+
+		subscription.workers <- struct{}{}
+
+		s.mu.Lock()
+		if subscription.unsubscribed {
+			s.mu.Unlock()
+			<-subscription.workers
+			continue
+		}
+		subscription.inflight.Add(1)
+		s.mu.Unlock()
+
+		s.wg.Add(1)
+
+		handler, workers, inflight := subscription.handler, subscription.workers, &subscription.inflight
+
+		handle := func() {
+			defer func() {
+				<-workers
+				inflight.Done()
+				s.wg.Done()
+			}()
+
+			if err := platform.HandleMessageWithMetadata(handler, d.body, d.metadata()); err != nil {
+				logger.WithError(err).WithField("routing_key", d.routingKey).Warn("failed to handle a delivery")
+			}
+		}
+
+		if len(subscription.lanes) > 0 {
+			subscription.lanes[subscription.ordering.Lane(d.body, d.metadata())].push(handle)
+		} else {
+			go handle()
+		}
+	}
+
+	if !wasHandled {
+		logger.WithField("routing_key", d.routingKey).WithField("reason", "undeliverable").Error("Failed to handle a message to this subscriber")
+	}
+}
+
+// Close stops consuming, gives the running handlers until the context is done
+// to finish and releases the queue, returning the context's error if they
+// didn't. Deliveries still waiting in a shared queue are left for the other
+// subscribers of that queue, the ones whose handlers didn't finish in time are
+// lost since the broker has no redelivery.
+func (s *Subscriber) Close(ctx context.Context) error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	s.mu.Unlock()
+
+	close(s.quit)
+	s.queue.wake()
+
+	drained := make(chan interface{})
+	go func() {
+		s.wg.Wait()
+		close(drained)
+	}()
+
+	var closeErr error
+
+	select {
+	case <-drained:
+	case <-ctx.Done():
+		closeErr = ctx.Err()
+	}
+
+	s.broker.releaseQueue(s.queue)
+
+	return closeErr
+}
+
+func newSubscriber(broker *Broker, queue string, exclusive, autoDelete bool) (*Subscriber, error) {
+	q, err := broker.declareQueue(queue, exclusive, autoDelete)
+	if err != nil {
+		return nil, err
+	}
+
+	return &Subscriber{
+		broker: broker,
+		queue:  q,
+		quit:   make(chan interface{}),
+	}, nil
+}
+
+// NewSubscriber consumes from a shared queue, every subscriber of the same
+// queue competes for its deliveries.
+func NewSubscriber(broker *Broker, queue string) (*Subscriber, error) {
+	return newSubscriber(broker, queue, false, false)
+}
+
+func NewExclusiveSubscriber(broker *Broker, queue string) (*Subscriber, error) {
+	return newSubscriber(broker, queue, true, false)
+}
+
+func NewAutoDeleteSubscriber(broker *Broker, queue string) (*Subscriber, error) {
+	return newSubscriber(broker, queue, false, true)
+}
@@ -0,0 +1,156 @@
+package platform
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/Sirupsen/logrus"
+)
+
+// Middleware wraps a handler to add behavior around it, such as logging,
+// authentication or validation.
+type Middleware func(Handler) Handler
+
+// Applies the middlewares so that the first one is the outermost
+func chainMiddleware(handler Handler, middlewares ...Middleware) Handler {
+	for i := len(middlewares) - 1; i >= 0; i-- {
+		handler = middlewares[i](handler)
+	}
+
+	return handler
+}
+
+type HandlerOption func(*handlerOptions)
+
+type handlerOptions struct {
+	middlewares      []Middleware
+	subscribeOptions []SubscribeOption
+}
+
+// WithMiddleware adds middleware to a single handler, it runs inside of the
+// middleware added through Service.Use.
+func WithMiddleware(middlewares ...Middleware) HandlerOption {
+	return func(options *handlerOptions) {
+		options.middlewares = append(options.middlewares, middlewares...)
+	}
+}
+
+// WithHandlerSubscribeOptions sizes the worker pool handling the requests of
+// a single handler, such as WithConcurrency for a handler that needs more
+// workers than the rest.
+func WithHandlerSubscribeOptions(subscribeOptions ...SubscribeOption) HandlerOption {
+	return func(options *handlerOptions) {
+		options.subscribeOptions = append(options.subscribeOptions, subscribeOptions...)
+	}
+}
+
+// Keeps track of the responses going through a responder, while still
+// exposing the context of the responder it wraps.
+type recordingResponder struct {
+	parent Responder
+
+	mu                sync.Mutex
+	totalResponses    int
+	lastResponseUri   string
+	responseCompleted bool
+}
+
+func (r *recordingResponder) Respond(response *Request) error {
+	r.mu.Lock()
+	r.totalResponses++
+	if len(response.GetRouting().GetRouteTo()) > 0 {
+		r.lastResponseUri = response.GetRouting().GetRouteTo()[0].GetUri()
+	}
+	r.responseCompleted = response.GetCompleted()
+	r.mu.Unlock()
+
+	return r.parent.Respond(response)
+}
+
+func (r *recordingResponder) Context() context.Context {
+	return ResponderContext(r.parent)
+}
+
+func requestUri(request *Request) string {
+	if len(request.GetRouting().GetRouteTo()) <= 0 {
+		return ""
+	}
+
+	return request.GetRouting().GetRouteTo()[0].GetUri()
+}
+
+// AccessLogMiddleware logs every handled request along with the last response
+// it produced and how long the handler took.
+func AccessLogMiddleware(next Handler) Handler {
+	return HandlerFunc(func(responder Responder, request *Request) {
+		recorder := &recordingResponder{parent: responder}
+		startedAt := time.Now()
+
+		next.ServePlatform(recorder, request)
+
+		recorder.mu.Lock()
+		defer recorder.mu.Unlock()
+
+		logger.WithFields(logrus.Fields{
+			"request_uuid":       request.GetUuid(),
+			"trace_uuid":         request.GetTrace().GetUuid(),
+			"uri":                requestUri(request),
+			"response_uri":       recorder.lastResponseUri,
+			"response_completed": recorder.responseCompleted,
+			"total_responses":    recorder.totalResponses,
+			"duration":           time.Since(startedAt).String(),
+		}).Info("handled request")
+	})
+}
+
+// TimingMiddleware reports how long the handler took for every request, keyed
+// by the uri the request was routed to.
+func TimingMiddleware(observe func(uri string, duration time.Duration)) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(responder Responder, request *Request) {
+			startedAt := time.Now()
+			defer func() {
+				observe(requestUri(request), time.Since(startedAt))
+			}()
+
+			next.ServePlatform(responder, request)
+		})
+	}
+}
+
+// RecoverMiddleware turns a panicking handler into an internal error reply,
+// then calls onPanic if it was provided. Panics are left alone when
+// PREVENT_PLATFORM_PANICS is disabled.
+func RecoverMiddleware(path string, onPanic func(request *Request, r interface{})) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(responder Responder, request *Request) {
+			defer capturePanic(func(r interface{}) {
+				logger.WithFields(logrus.Fields{
+					"resource_type": "handler",
+					"path":          path,
+					"reason":        r,
+				}).Error("Service has panicked!")
+
+				panicLocation := identifyPanic()
+
+				responder.Respond(newErrorResponse(&Error{
+					Message: String(fmt.Sprintf("A fatal error has occurred. %s: %s %s", path, panicLocation, r)),
+					Code:    Error_INTERNAL.Enum(),
+					Details: map[string]string{
+						"path":     path,
+						"location": panicLocation,
+						"reason":   fmt.Sprint(r),
+					},
+				}))
+
+				if onPanic != nil {
+					onPanic(request, r)
+				}
+			})
+
+			next.ServePlatform(responder, request)
+		})
+	}
+}
@@ -0,0 +1,117 @@
+package platform
+
+import (
+	"testing"
+	"time"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newRecordingMiddleware(name string, calls *[]string) Middleware {
+	return func(next Handler) Handler {
+		return HandlerFunc(func(responder Responder, request *Request) {
+			*calls = append(*calls, name)
+
+			next.ServePlatform(responder, request)
+		})
+	}
+}
+
+func TestServiceMiddleware(t *testing.T) {
+	Convey("Service middleware should run before handler middleware, in the order it was added", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+		mockResponder := newMockResponder()
+
+		service, err := NewServiceWithResponder("test-service", mockPublisher, mockSubscriber, nil, mockResponder)
+		So(err, ShouldBeNil)
+
+		calls := []string{}
+
+		service.Use(newRecordingMiddleware("first", &calls), newRecordingMiddleware("second", &calls))
+		service.AddHandler("testing", HandlerFunc(func(responder Responder, request *Request) {
+			calls = append(calls, "handler")
+
+			responder.Respond(&Request{
+				Routing:   RouteToUri("resource:///teltech/reply/foobar"),
+				Completed: Bool(true),
+			})
+		}), WithMiddleware(newRecordingMiddleware("handler-only", &calls)))
+
+		requestBytes, _ := Marshal(&Request{
+			Routing: RouteToUri("microservice:///teltech/get/foobar"),
+		})
+
+		So(mockSubscriber.topicHandlers["microservice-testing"][0].HandleMessage(requestBytes), ShouldBeNil)
+		So(calls, ShouldResemble, []string{"first", "second", "handler-only", "handler"})
+		So(len(mockResponder.requests), ShouldEqual, 1)
+	})
+
+	Convey("A panicking middleware should still be turned into a platform error", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+		mockResponder := newMockResponder()
+
+		service, err := NewServiceWithResponder("test-service", mockPublisher, mockSubscriber, nil, mockResponder)
+		So(err, ShouldBeNil)
+
+		service.Use(func(next Handler) Handler {
+			return HandlerFunc(func(responder Responder, request *Request) {
+				panic("middleware failure")
+			})
+		})
+		service.AddHandler("testing", HandlerFunc(func(responder Responder, request *Request) {}))
+
+		So(mockSubscriber.topicHandlers["microservice-testing"][0].HandleMessage([]byte{}), ShouldBeNil)
+
+		So(len(mockPublisher.mockPublishes), ShouldEqual, 1)
+		So(mockPublisher.mockPublishes[0].topic, ShouldEqual, "panic.handler.testing")
+
+		So(len(mockResponder.requests), ShouldEqual, 1)
+		So(NewPlatformErrorFromResponse(mockResponder.requests[0]).Details["reason"], ShouldEqual, "middleware failure")
+	})
+}
+
+func TestBuiltinMiddleware(t *testing.T) {
+	Convey("The access log middleware should pass responses and the context through", t, func() {
+		mockResponder := newMockResponder()
+		request := &Request{
+			Uuid:    String("request-uuid"),
+			Routing: RouteToUri("microservice:///teltech/get/foobar"),
+		}
+		requestResponder := NewRequestResponder(mockResponder, request)
+
+		handler := AccessLogMiddleware(HandlerFunc(func(responder Responder, request *Request) {
+			So(ResponderContext(responder), ShouldEqual, requestResponder.Context())
+
+			responder.Respond(&Request{
+				Routing:   RouteToUri("resource:///teltech/reply/foobar"),
+				Completed: Bool(true),
+			})
+		}))
+
+		handler.ServePlatform(requestResponder, request)
+
+		So(len(mockResponder.requests), ShouldEqual, 1)
+		So(mockResponder.requests[0].GetCompleted(), ShouldBeTrue)
+	})
+
+	Convey("The timing middleware should observe every request", t, func() {
+		observedUri := ""
+		var observedDuration time.Duration
+
+		handler := TimingMiddleware(func(uri string, duration time.Duration) {
+			observedUri = uri
+			observedDuration = duration
+		})(HandlerFunc(func(responder Responder, request *Request) {
+			time.Sleep(10 * time.Millisecond)
+		}))
+
+		handler.ServePlatform(newMockResponder(), &Request{
+			Routing: RouteToUri("microservice:///teltech/get/foobar"),
+		})
+
+		So(observedUri, ShouldEqual, "microservice:///teltech/get/foobar")
+		So(observedDuration, ShouldBeGreaterThanOrEqualTo, 10*time.Millisecond)
+	})
+}
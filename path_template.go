@@ -0,0 +1,191 @@
+package platform
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Splits a path into its segments, ignoring empty ones so that /a/b, a/b and
+// /a/b/ are all the same path.
+func pathSegments(path string) []string {
+	segments := []string{}
+
+	for _, segment := range strings.Split(path, "/") {
+		if segment != "" {
+			segments = append(segments, segment)
+		}
+	}
+
+	return segments
+}
+
+// Dots separate the words of a routing key, so they are escaped within a
+// segment, along with the escape character itself.
+func escapeRoutingKeySegment(segment string) string {
+	return strings.Replace(strings.Replace(segment, "%", "%25", -1), ".", "%2E", -1)
+}
+
+// LegacyRoutingKey builds the routing key that requests for a uri were always
+// published with, e.g. microservice:///teltech/get/foobar is published on
+// microservice-/teltech/get/foobar. Services bind it for their exact paths, so
+// that routers set to publish it still reach them.
+func LegacyRoutingKey(scheme, path string) string {
+	return scheme + "-" + path
+}
+
+// RoutingKey builds the dotted routing key that requests for a uri are
+// published with, e.g. microservice:///teltech/get/foobar is published on
+// microservice.teltech.get.foobar. Unlike the legacy microservice-<path> keys,
+// every path segment is its own word so that services can bind wildcards.
+func RoutingKey(scheme, path string) string {
+	routingKey := scheme
+
+	for _, segment := range pathSegments(path) {
+		routingKey += "." + escapeRoutingKeySegment(segment)
+	}
+
+	return routingKey
+}
+
+// PathTemplate is a handler path that may contain {name} segments capturing a
+// single segment, * segments matching any single segment and a trailing **
+// matching any number of segments, e.g. /users/{id}/orders.
+type PathTemplate struct {
+	path     string
+	segments []string
+}
+
+func (t *PathTemplate) String() string {
+	return t.path
+}
+
+// IsExact reports whether the template only matches its own path
+func (t *PathTemplate) IsExact() bool {
+	for _, segment := range t.segments {
+		if isPathParam(segment) || segment == "*" || segment == "**" {
+			return false
+		}
+	}
+
+	return true
+}
+
+// RoutingKey returns the binding that receives every request the template
+// matches, with parameters and wildcards turned into topic wildcards.
+func (t *PathTemplate) RoutingKey(scheme string) string {
+	routingKey := scheme
+
+	for _, segment := range t.segments {
+		switch {
+		case isPathParam(segment) || segment == "*":
+			routingKey += ".*"
+		case segment == "**":
+			routingKey += ".#"
+		default:
+			routingKey += "." + escapeRoutingKeySegment(segment)
+		}
+	}
+
+	return routingKey
+}
+
+// Match returns the parameters captured from the path, if it matches
+func (t *PathTemplate) Match(path string) (map[string]string, bool) {
+	segments := pathSegments(path)
+	params := map[string]string{}
+
+	for i, templateSegment := range t.segments {
+		if templateSegment == "**" {
+			return params, true
+		}
+
+		if i >= len(segments) {
+			return nil, false
+		}
+
+		switch {
+		case isPathParam(templateSegment):
+			params[templateSegment[1:len(templateSegment)-1]] = segments[i]
+		case templateSegment == "*":
+		case templateSegment != segments[i]:
+			return nil, false
+		}
+	}
+
+	if len(segments) != len(t.segments) {
+		return nil, false
+	}
+
+	return params, true
+}
+
+// MoreSpecificThan tells which of two templates matching the same path serves
+// it. Going segment by segment, the first literal beats a parameter or a *,
+// which beats a trailing **.
+func (t *PathTemplate) MoreSpecificThan(other *PathTemplate) bool {
+	for i := 0; i < len(t.segments) && i < len(other.segments); i++ {
+		if rank, otherRank := segmentRank(t.segments[i]), segmentRank(other.segments[i]); rank != otherRank {
+			return rank > otherRank
+		}
+	}
+
+	// The longer template can only go on with a ** that matches nothing here
+	return len(t.segments) < len(other.segments)
+}
+
+func segmentRank(segment string) int {
+	switch {
+	case segment == "**":
+		return 0
+	case isPathParam(segment) || segment == "*":
+		return 1
+	default:
+		return 2
+	}
+}
+
+func isPathParam(segment string) bool {
+	return strings.HasPrefix(segment, "{") && strings.HasSuffix(segment, "}")
+}
+
+func ParsePathTemplate(path string) (*PathTemplate, error) {
+	segments := pathSegments(path)
+	names := map[string]bool{}
+
+	for i, segment := range segments {
+		switch {
+		case isPathParam(segment):
+			name := segment[1 : len(segment)-1]
+			if name == "" || strings.ContainsAny(name, "{}*#") {
+				return nil, fmt.Errorf("invalid path parameter %s in %s", segment, path)
+			}
+
+			if names[name] {
+				return nil, fmt.Errorf("duplicate path parameter %s in %s", segment, path)
+			}
+
+			names[name] = true
+
+		case segment == "*":
+
+		case segment == "**":
+			if i != len(segments)-1 {
+				return nil, fmt.Errorf("** must be the last segment of %s", path)
+			}
+
+		case strings.ContainsAny(segment, "{}*#"):
+			return nil, fmt.Errorf("invalid path segment %s in %s", segment, path)
+		}
+	}
+
+	return &PathTemplate{
+		path:     path,
+		segments: segments,
+	}, nil
+}
+
+// PathParam returns a parameter captured by the template of the handler that
+// is serving the request.
+func PathParam(request *Request, name string) string {
+	return request.GetPathParams()[name]
+}
@@ -0,0 +1,85 @@
+package platform
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestLegacyRoutingKey(t *testing.T) {
+	Convey("Legacy routing keys should keep the whole path as a single word", t, func() {
+		So(LegacyRoutingKey("microservice", "/teltech/get/foobar"), ShouldEqual, "microservice-/teltech/get/foobar")
+	})
+}
+
+func TestRoutingKey(t *testing.T) {
+	Convey("Every path segment should be a word of the routing key", t, func() {
+		So(RoutingKey("microservice", "/teltech/get/foobar"), ShouldEqual, "microservice.teltech.get.foobar")
+		So(RoutingKey("microservice", "testing"), ShouldEqual, "microservice.testing")
+		So(RoutingKey("microservice", "/files/report.pdf"), ShouldEqual, "microservice.files.report%2Epdf")
+		So(RoutingKey("microservice", "/"), ShouldEqual, "microservice")
+	})
+}
+
+func TestPathTemplate(t *testing.T) {
+	Convey("Templates should turn into topic wildcards and capture parameters", t, func() {
+		pathTemplate, err := ParsePathTemplate("/users/{id}/orders/{order_id}")
+		So(err, ShouldBeNil)
+		So(pathTemplate.IsExact(), ShouldBeFalse)
+		So(pathTemplate.RoutingKey("microservice"), ShouldEqual, "microservice.users.*.orders.*")
+
+		params, matches := pathTemplate.Match("/users/42/orders/7")
+		So(matches, ShouldBeTrue)
+		So(params, ShouldResemble, map[string]string{"id": "42", "order_id": "7"})
+
+		_, matches = pathTemplate.Match("/users/42/orders")
+		So(matches, ShouldBeFalse)
+
+		_, matches = pathTemplate.Match("/users/42/invoices/7")
+		So(matches, ShouldBeFalse)
+	})
+
+	Convey("Wildcards should match without capturing", t, func() {
+		pathTemplate, err := ParsePathTemplate("/users/*/events/**")
+		So(err, ShouldBeNil)
+		So(pathTemplate.RoutingKey("microservice"), ShouldEqual, "microservice.users.*.events.#")
+
+		params, matches := pathTemplate.Match("/users/42/events/created/today")
+		So(matches, ShouldBeTrue)
+		So(params, ShouldBeEmpty)
+
+		_, matches = pathTemplate.Match("/users/42/events")
+		So(matches, ShouldBeTrue)
+	})
+
+	Convey("Exact paths should keep matching themselves only", t, func() {
+		pathTemplate, err := ParsePathTemplate("/teltech/get/foobar")
+		So(err, ShouldBeNil)
+		So(pathTemplate.IsExact(), ShouldBeTrue)
+		So(pathTemplate.RoutingKey("microservice"), ShouldEqual, RoutingKey("microservice", "/teltech/get/foobar"))
+	})
+
+	Convey("Literal segments should be more specific than parameters, and parameters than a trailing **", t, func() {
+		parse := func(path string) *PathTemplate {
+			pathTemplate, err := ParsePathTemplate(path)
+			So(err, ShouldBeNil)
+
+			return pathTemplate
+		}
+
+		So(parse("/users/me").MoreSpecificThan(parse("/users/{id}")), ShouldBeTrue)
+		So(parse("/users/{id}").MoreSpecificThan(parse("/users/me")), ShouldBeFalse)
+		So(parse("/users/{id}").MoreSpecificThan(parse("/users/**")), ShouldBeTrue)
+		So(parse("/users").MoreSpecificThan(parse("/users/**")), ShouldBeTrue)
+		So(parse("/users/**").MoreSpecificThan(parse("/users")), ShouldBeFalse)
+		So(parse("/users/{id}").MoreSpecificThan(parse("/users/*")), ShouldBeFalse)
+		So(parse("/users/*").MoreSpecificThan(parse("/users/{id}")), ShouldBeFalse)
+	})
+
+	Convey("Invalid templates should be rejected", t, func() {
+		for _, path := range []string{"/users/{}", "/users/{id}/{id}", "/users/**/orders", "/users/a*b", "/users/{id"} {
+			_, err := ParsePathTemplate(path)
+			So(err, ShouldNotBeNil)
+		}
+	})
+}
@@ -37,6 +37,64 @@ var _ = math.Inf
 // is compatible with the proto package it is being compiled against.
 const _ = proto.ProtoPackageIsVersion1
 
+type Error_Code int32
+
+const (
+	Error_UNKNOWN           Error_Code = 0
+	Error_INTERNAL          Error_Code = 1
+	Error_INVALID_ARGUMENT  Error_Code = 2
+	Error_NOT_FOUND         Error_Code = 3
+	Error_ALREADY_EXISTS    Error_Code = 4
+	Error_PERMISSION_DENIED Error_Code = 5
+	Error_UNAUTHENTICATED   Error_Code = 6
+	Error_DEADLINE_EXCEEDED Error_Code = 7
+	Error_CANCELLED         Error_Code = 8
+	Error_UNAVAILABLE       Error_Code = 9
+)
+
+var Error_Code_name = map[int32]string{
+	0: "UNKNOWN",
+	1: "INTERNAL",
+	2: "INVALID_ARGUMENT",
+	3: "NOT_FOUND",
+	4: "ALREADY_EXISTS",
+	5: "PERMISSION_DENIED",
+	6: "UNAUTHENTICATED",
+	7: "DEADLINE_EXCEEDED",
+	8: "CANCELLED",
+	9: "UNAVAILABLE",
+}
+var Error_Code_value = map[string]int32{
+	"UNKNOWN":           0,
+	"INTERNAL":          1,
+	"INVALID_ARGUMENT":  2,
+	"NOT_FOUND":         3,
+	"ALREADY_EXISTS":    4,
+	"PERMISSION_DENIED": 5,
+	"UNAUTHENTICATED":   6,
+	"DEADLINE_EXCEEDED": 7,
+	"CANCELLED":         8,
+	"UNAVAILABLE":       9,
+}
+
+func (x Error_Code) Enum() *Error_Code {
+	p := new(Error_Code)
+	*p = x
+	return p
+}
+func (x Error_Code) String() string {
+	return proto.EnumName(Error_Code_name, int32(x))
+}
+func (x *Error_Code) UnmarshalJSON(data []byte) error {
+	value, err := proto.UnmarshalJSONEnum(Error_Code_value, data, "Error_Code")
+	if err != nil {
+		return err
+	}
+	*x = Error_Code(value)
+	return nil
+}
+func (Error_Code) EnumDescriptor() ([]byte, []int) { return fileDescriptor0, []int{2, 0} }
+
 type IpAddress_Version int32
 
 const (
@@ -185,8 +243,12 @@ func (m *DocumentationList) GetDocumentations() []*Documentation {
 }
 
 type Error struct {
-	Message          *string `protobuf:"bytes,1,opt,name=message" json:"message,omitempty"`
-	XXX_unrecognized []byte  `json:"-"`
+	Message          *string           `protobuf:"bytes,1,opt,name=message" json:"message,omitempty"`
+	Code             *Error_Code       `protobuf:"varint,2,opt,name=code,enum=platform.Error_Code" json:"code,omitempty"`
+	Retryable        *bool             `protobuf:"varint,3,opt,name=retryable" json:"retryable,omitempty"`
+	Details          map[string]string `protobuf:"bytes,4,rep,name=details" json:"details,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	Cause            *Error            `protobuf:"bytes,5,opt,name=cause" json:"cause,omitempty"`
+	XXX_unrecognized []byte            `json:"-"`
 }
 
 func (m *Error) Reset()                    { *m = Error{} }
@@ -201,6 +263,34 @@ func (m *Error) GetMessage() string {
 	return ""
 }
 
+func (m *Error) GetCode() Error_Code {
+	if m != nil && m.Code != nil {
+		return *m.Code
+	}
+	return Error_UNKNOWN
+}
+
+func (m *Error) GetRetryable() bool {
+	if m != nil && m.Retryable != nil {
+		return *m.Retryable
+	}
+	return false
+}
+
+func (m *Error) GetDetails() map[string]string {
+	if m != nil {
+		return m.Details
+	}
+	return nil
+}
+
+func (m *Error) GetCause() *Error {
+	if m != nil {
+		return m.Cause
+	}
+	return nil
+}
+
 type IpAddress struct {
 	Address          *string            `protobuf:"bytes,1,opt,name=address" json:"address,omitempty"`
 	Version          *IpAddress_Version `protobuf:"varint,2,opt,name=version,enum=platform.IpAddress_Version" json:"version,omitempty"`
@@ -227,13 +317,15 @@ func (m *IpAddress) GetVersion() IpAddress_Version {
 }
 
 type Request struct {
-	Uuid             *string  `protobuf:"bytes,1,opt,name=uuid" json:"uuid,omitempty"`
-	Routing          *Routing `protobuf:"bytes,2,opt,name=routing" json:"routing,omitempty"`
-	Context          []byte   `protobuf:"bytes,3,opt,name=context" json:"context,omitempty"`
-	Payload          []byte   `protobuf:"bytes,4,opt,name=payload" json:"payload,omitempty"`
-	Completed        *bool    `protobuf:"varint,5,opt,name=completed" json:"completed,omitempty"`
-	Trace            *Trace   `protobuf:"bytes,6,opt,name=trace" json:"trace,omitempty"`
-	XXX_unrecognized []byte   `json:"-"`
+	Uuid             *string           `protobuf:"bytes,1,opt,name=uuid" json:"uuid,omitempty"`
+	Routing          *Routing          `protobuf:"bytes,2,opt,name=routing" json:"routing,omitempty"`
+	Context          []byte            `protobuf:"bytes,3,opt,name=context" json:"context,omitempty"`
+	Payload          []byte            `protobuf:"bytes,4,opt,name=payload" json:"payload,omitempty"`
+	Completed        *bool             `protobuf:"varint,5,opt,name=completed" json:"completed,omitempty"`
+	Trace            *Trace            `protobuf:"bytes,6,opt,name=trace" json:"trace,omitempty"`
+	Deadline         *string           `protobuf:"bytes,7,opt,name=deadline" json:"deadline,omitempty"`
+	PathParams       map[string]string `protobuf:"bytes,8,rep,name=path_params" json:"path_params,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	XXX_unrecognized []byte            `json:"-"`
 }
 
 func (m *Request) Reset()                    { *m = Request{} }
@@ -283,6 +375,20 @@ func (m *Request) GetTrace() *Trace {
 	return nil
 }
 
+func (m *Request) GetDeadline() string {
+	if m != nil && m.Deadline != nil {
+		return *m.Deadline
+	}
+	return ""
+}
+
+func (m *Request) GetPathParams() map[string]string {
+	if m != nil {
+		return m.PathParams
+	}
+	return nil
+}
+
 type Route struct {
 	Uri              *string    `protobuf:"bytes,1,opt,name=uri" json:"uri,omitempty"`
 	IpAddress        *IpAddress `protobuf:"bytes,2,opt,name=ip_address" json:"ip_address,omitempty"`
@@ -527,52 +633,72 @@ func init() {
 	proto.RegisterType((*ServiceRoute)(nil), "platform.ServiceRoute")
 	proto.RegisterType((*Trace)(nil), "platform.Trace")
 	proto.RegisterType((*TraceList)(nil), "platform.TraceList")
+	proto.RegisterEnum("platform.Error_Code", Error_Code_name, Error_Code_value)
 	proto.RegisterEnum("platform.IpAddress_Version", IpAddress_Version_name, IpAddress_Version_value)
 	proto.RegisterEnum("platform.RouterConfig_RouterType", RouterConfig_RouterType_name, RouterConfig_RouterType_value)
 	proto.RegisterEnum("platform.RouterConfig_ProtocolType", RouterConfig_ProtocolType_name, RouterConfig_ProtocolType_value)
 }
 
 var fileDescriptor0 = []byte{
-	// 654 bytes of a gzipped FileDescriptorProto
-	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x09, 0x6e, 0x88, 0x02, 0xff, 0x74, 0x53, 0xcb, 0x6e, 0xd3, 0x40,
-	0x14, 0xc5, 0x79, 0xfb, 0x26, 0x4d, 0xdc, 0x29, 0x6d, 0x5d, 0x21, 0x41, 0x3b, 0x5d, 0xd0, 0x45,
-	0x15, 0xa4, 0x08, 0x75, 0x81, 0x84, 0x10, 0x4d, 0x23, 0x40, 0x54, 0x4a, 0x70, 0x4c, 0x11, 0x2b,
-	0xcb, 0xb2, 0xa7, 0xc5, 0x52, 0xe2, 0x19, 0x66, 0x26, 0x15, 0xfd, 0x0b, 0xf8, 0x17, 0x3e, 0x90,
-	0x79, 0xb8, 0x75, 0x92, 0x86, 0x95, 0x7d, 0x1f, 0x73, 0xee, 0xeb, 0x1c, 0xe8, 0xb2, 0x59, 0x2c,
-	0xaf, 0x29, 0x9f, 0xf7, 0x19, 0xa7, 0x92, 0xa2, 0xd6, 0xbd, 0x8d, 0x43, 0xd8, 0xba, 0xa0, 0xc9,
-	0x62, 0x4e, 0x72, 0x19, 0xcb, 0x8c, 0xe6, 0x68, 0x07, 0xda, 0x29, 0x11, 0x09, 0xcf, 0x98, 0x36,
-	0x7d, 0xe7, 0xd0, 0x39, 0x71, 0x51, 0x1f, 0xba, 0x82, 0xf0, 0xdb, 0x2c, 0x21, 0x11, 0xa7, 0x0b,
-	0x49, 0x84, 0x5f, 0x39, 0xac, 0x9e, 0xb4, 0x07, 0x7b, 0xfd, 0x07, 0xe0, 0xa9, 0x8d, 0x07, 0x3a,
-	0x8c, 0x2f, 0x60, 0x7b, 0x05, 0xf5, 0x32, 0x13, 0x12, 0xbd, 0x82, 0x6e, 0xba, 0xec, 0x14, 0x0a,
-	0x5c, 0x83, 0xec, 0x97, 0x20, 0x2b, 0x8f, 0xb0, 0x0f, 0xf5, 0x11, 0xe7, 0x94, 0xa3, 0x1e, 0x34,
-	0xe7, 0x44, 0x88, 0xf8, 0x86, 0xd8, 0x7e, 0x30, 0x01, 0xf7, 0x13, 0x7b, 0x9f, 0xa6, 0x5c, 0x79,
-	0x75, 0x34, 0xb6, 0xbf, 0x45, 0xb7, 0xa7, 0xd0, 0xbc, 0x25, 0x5c, 0xe8, 0xf6, 0x2b, 0xca, 0xd1,
-	0x1d, 0x3c, 0x2b, 0x2b, 0x3c, 0x3c, 0xeb, 0x5f, 0xd9, 0x14, 0x7c, 0x00, 0xcd, 0xe2, 0x17, 0x35,
-	0xa0, 0x72, 0xf5, 0xda, 0x7b, 0x62, 0xbe, 0x67, 0x9e, 0x83, 0x7f, 0x3b, 0xd0, 0x0c, 0xc8, 0xcf,
-	0x05, 0x51, 0xdd, 0x77, 0xa0, 0xb6, 0x58, 0x64, 0x69, 0x51, 0x02, 0x43, 0x53, 0x2f, 0x22, 0xcb,
-	0x6f, 0x4c, 0x89, 0xf6, 0x60, 0xbb, 0x2c, 0x11, 0xd8, 0x80, 0xee, 0x2b, 0xa1, 0xb9, 0x24, 0xbf,
-	0xa4, 0x5f, 0x55, 0x39, 0x1d, 0xed, 0x60, 0xf1, 0xdd, 0x8c, 0xc6, 0xa9, 0x5f, 0x33, 0x8e, 0x6d,
-	0x70, 0x13, 0x3a, 0x67, 0x33, 0x22, 0x49, 0xea, 0xd7, 0x95, 0xab, 0x85, 0x9e, 0x43, 0x5d, 0xf2,
-	0x38, 0x21, 0x7e, 0xc3, 0xc0, 0xf6, 0x4a, 0xd8, 0x50, 0xbb, 0xf1, 0x5b, 0xa8, 0x9b, 0x15, 0xa3,
-	0x36, 0x54, 0x17, 0x3c, 0x2b, 0xda, 0x79, 0x09, 0x90, 0xb1, 0xe8, 0x7e, 0x0b, 0xb6, 0xa3, 0x9d,
-	0x0d, 0x43, 0xe3, 0x2f, 0x6a, 0xa0, 0xa2, 0xbd, 0x23, 0x68, 0x99, 0x5b, 0x46, 0x92, 0x16, 0x87,
-	0xe8, 0xad, 0xce, 0x40, 0xd0, 0x31, 0x80, 0x4d, 0xb9, 0xe6, 0x74, 0x5e, 0x9c, 0x7c, 0x3d, 0x09,
-	0xff, 0xad, 0x40, 0xc7, 0xfc, 0xf1, 0x21, 0xcd, 0xaf, 0xb3, 0x1b, 0xf4, 0x06, 0xb6, 0x0c, 0xcb,
-	0x12, 0x3a, 0x8b, 0xe4, 0x1d, 0xb3, 0x37, 0xeb, 0x0e, 0x8e, 0xd7, 0x1e, 0x16, 0xe9, 0xfd, 0x49,
-	0x91, 0x1b, 0xaa, 0x54, 0xbd, 0xe5, 0x1f, 0x54, 0x48, 0x33, 0x82, 0xab, 0x2d, 0x46, 0xb9, 0x5d,
-	0x9f, 0x8b, 0xce, 0xa0, 0x6d, 0xba, 0xe1, 0x16, 0xb5, 0x66, 0x50, 0x8f, 0xfe, 0x83, 0x6a, 0x0d,
-	0x8d, 0x89, 0xa7, 0x00, 0xa5, 0x85, 0x0e, 0x60, 0x37, 0x18, 0x7f, 0x0d, 0x47, 0x41, 0x14, 0x7e,
-	0x9f, 0x8c, 0xa2, 0x6f, 0xa3, 0xf3, 0xe9, 0x78, 0xf8, 0x79, 0x14, 0x7a, 0x0e, 0x7a, 0x0a, 0xde,
-	0x72, 0xe8, 0x43, 0x30, 0x19, 0x7a, 0x95, 0x75, 0xef, 0xc7, 0x30, 0x9c, 0x78, 0x55, 0xfc, 0x0e,
-	0x3a, 0x2b, 0x8d, 0xef, 0x01, 0x9a, 0x04, 0xe3, 0x70, 0x3c, 0x1c, 0x5f, 0x2e, 0xe5, 0x39, 0x68,
-	0x1f, 0x76, 0x1e, 0xfb, 0xa7, 0x5e, 0x05, 0x9f, 0x2b, 0xd8, 0xa5, 0x86, 0x8d, 0x42, 0x94, 0xcc,
-	0x8a, 0x09, 0x13, 0xe3, 0xbc, 0x57, 0xc8, 0xde, 0xe6, 0x21, 0xf1, 0x1f, 0x07, 0x3a, 0xcb, 0xba,
-	0xdb, 0x2c, 0xde, 0x43, 0xc5, 0x55, 0x4b, 0xe2, 0x82, 0x19, 0x8f, 0xee, 0x8c, 0xc1, 0x55, 0xec,
-	0x60, 0x4a, 0x92, 0x4a, 0xd9, 0xd5, 0xcd, 0x5c, 0xd8, 0x85, 0xad, 0x4c, 0x44, 0x29, 0x61, 0x9c,
-	0x24, 0xb1, 0xe6, 0x6b, 0xcd, 0xf0, 0xb5, 0x57, 0x6a, 0xad, 0x6e, 0xa4, 0x79, 0x0b, 0x75, 0xc3,
-	0xd4, 0x35, 0xc1, 0x28, 0x2b, 0x8f, 0xe7, 0xa4, 0x38, 0xac, 0x22, 0xbe, 0x60, 0x71, 0x1e, 0x99,
-	0x04, 0x7b, 0x5d, 0x1f, 0x3c, 0x16, 0x73, 0xa5, 0xfd, 0xa8, 0x8c, 0xd4, 0x4c, 0x04, 0x01, 0x08,
-	0x19, 0x73, 0x19, 0xc9, 0x4c, 0x01, 0x98, 0x2a, 0xc8, 0x83, 0x16, 0xc9, 0x53, 0xeb, 0x69, 0x98,
-	0xba, 0xa7, 0xe0, 0x9a, 0xba, 0x66, 0x91, 0x2f, 0xa0, 0x61, 0x54, 0x24, 0x1e, 0x33, 0xdb, 0x24,
-	0xfd, 0x0b, 0x00, 0x00, 0xff, 0xff, 0x05, 0x9c, 0x53, 0x81, 0x11, 0x05, 0x00, 0x00,
+	// 947 bytes of a gzipped FileDescriptorProto
+	0x1f, 0x8b, 0x08, 0x00, 0x00, 0x00, 0x00, 0x00, 0x02, 0xff, 0x84, 0x54, 0xcd, 0x6e, 0xdb, 0x46,
+	0x10, 0x0e, 0xf5, 0x63, 0x4a, 0x23, 0x59, 0xa2, 0xd7, 0x89, 0xc3, 0xa4, 0x45, 0x2b, 0x33, 0x87,
+	0xfa, 0x90, 0xaa, 0xa8, 0x51, 0x04, 0x45, 0x80, 0xa2, 0xa0, 0xc5, 0x6d, 0x42, 0x84, 0xa1, 0x54,
+	0x8a, 0x72, 0x92, 0x13, 0xb1, 0x25, 0xd7, 0x36, 0x51, 0x89, 0x64, 0x77, 0x57, 0x46, 0xf5, 0x10,
+	0x2d, 0xd0, 0x77, 0xe9, 0xb5, 0xef, 0x56, 0x70, 0x97, 0xb6, 0x7e, 0xac, 0xa2, 0x27, 0x72, 0x67,
+	0xbe, 0x9d, 0x99, 0x9d, 0x6f, 0xbe, 0x81, 0x5e, 0x31, 0x27, 0xe2, 0x2a, 0x67, 0x8b, 0x61, 0xc1,
+	0x72, 0x91, 0xa3, 0xd6, 0xdd, 0xd9, 0x0a, 0xe1, 0xd0, 0xc9, 0xe3, 0xe5, 0x82, 0x66, 0x82, 0x88,
+	0x34, 0xcf, 0xd0, 0x31, 0x74, 0x12, 0xca, 0x63, 0x96, 0x16, 0xe5, 0xd1, 0xd4, 0x06, 0xda, 0x59,
+	0x1b, 0x0d, 0xa1, 0xc7, 0x29, 0xbb, 0x4d, 0x63, 0x1a, 0xb1, 0x7c, 0x29, 0x28, 0x37, 0x6b, 0x83,
+	0xfa, 0x59, 0xe7, 0xfc, 0x64, 0x78, 0x1f, 0x78, 0xaa, 0xfc, 0x41, 0xe9, 0xb6, 0x1c, 0x38, 0xda,
+	0x8a, 0xea, 0xa5, 0x5c, 0xa0, 0x6f, 0xa0, 0x97, 0x6c, 0x1a, 0xb9, 0xa9, 0xc9, 0x20, 0x4f, 0xd7,
+	0x41, 0xb6, 0x2e, 0x59, 0x7f, 0xd6, 0xa1, 0x89, 0x19, 0xcb, 0x19, 0xea, 0x83, 0xbe, 0xa0, 0x9c,
+	0x93, 0x6b, 0x5a, 0x15, 0x64, 0x41, 0x23, 0xce, 0x13, 0x6a, 0xd6, 0x06, 0xda, 0x59, 0xef, 0xfc,
+	0xf1, 0x3a, 0x82, 0xc4, 0x0f, 0x47, 0x79, 0x42, 0xd1, 0x11, 0xb4, 0x19, 0x15, 0x6c, 0x45, 0x7e,
+	0x99, 0x53, 0xb3, 0x3e, 0xd0, 0xce, 0x5a, 0xe8, 0x6b, 0xd0, 0x13, 0x2a, 0x48, 0x3a, 0xe7, 0x66,
+	0x43, 0xe6, 0xfe, 0x7c, 0xf7, 0xa6, 0xa3, 0xdc, 0x38, 0x13, 0x6c, 0x85, 0xbe, 0x80, 0x66, 0x4c,
+	0x96, 0x9c, 0x9a, 0xcd, 0x81, 0x76, 0xd6, 0x39, 0xef, 0xef, 0x80, 0x9f, 0x0f, 0xa1, 0xbb, 0x85,
+	0xef, 0x40, 0xfd, 0x57, 0xba, 0xaa, 0x4a, 0x3c, 0x84, 0xe6, 0x2d, 0x99, 0x2f, 0x55, 0x8d, 0xed,
+	0xd7, 0xb5, 0xef, 0x35, 0xeb, 0x1f, 0x0d, 0x1a, 0xb2, 0xb4, 0x0e, 0xe8, 0x33, 0xff, 0x9d, 0x3f,
+	0xfe, 0xe0, 0x1b, 0x8f, 0x50, 0x17, 0x5a, 0xae, 0x1f, 0xe2, 0xc0, 0xb7, 0x3d, 0x43, 0x43, 0x8f,
+	0xc1, 0x70, 0xfd, 0x4b, 0xdb, 0x73, 0x9d, 0xc8, 0x0e, 0xde, 0xcc, 0xde, 0x63, 0x3f, 0x34, 0x6a,
+	0xe8, 0x10, 0xda, 0xfe, 0x38, 0x8c, 0x7e, 0x1a, 0xcf, 0x7c, 0xc7, 0xa8, 0x23, 0x04, 0x3d, 0xdb,
+	0x0b, 0xb0, 0xed, 0x7c, 0x8a, 0xf0, 0x47, 0x77, 0x1a, 0x4e, 0x8d, 0x06, 0x7a, 0x02, 0x47, 0x13,
+	0x1c, 0xbc, 0x77, 0xa7, 0x53, 0x77, 0xec, 0x47, 0x0e, 0xf6, 0x5d, 0xec, 0x18, 0x4d, 0x74, 0x0c,
+	0xfd, 0x99, 0x6f, 0xcf, 0xc2, 0xb7, 0xd8, 0x0f, 0xdd, 0x91, 0x1d, 0x62, 0xc7, 0x38, 0x28, 0xb1,
+	0x0e, 0xb6, 0x1d, 0xcf, 0xf5, 0x71, 0x84, 0x3f, 0x8e, 0x30, 0x76, 0xb0, 0x63, 0xe8, 0x65, 0x96,
+	0x91, 0xed, 0x8f, 0xb0, 0xe7, 0x61, 0xc7, 0x68, 0xa1, 0x3e, 0x74, 0x66, 0xbe, 0x7d, 0x69, 0xbb,
+	0x9e, 0x7d, 0xe1, 0x61, 0xa3, 0x6d, 0x51, 0x68, 0xbb, 0x85, 0x9d, 0x24, 0x8c, 0x72, 0x5e, 0x72,
+	0x42, 0xd4, 0x6f, 0xf5, 0xe0, 0x97, 0xa0, 0xdf, 0x52, 0xc6, 0xcb, 0xa9, 0x51, 0xb4, 0x7c, 0xb6,
+	0xee, 0xd7, 0xfd, 0xb5, 0xe1, 0xa5, 0x82, 0x58, 0xcf, 0x40, 0xaf, 0x7e, 0xd1, 0x01, 0xd4, 0x2e,
+	0xbf, 0x33, 0x1e, 0xc9, 0xef, 0x2b, 0x43, 0xb3, 0xfe, 0xa8, 0x81, 0x1e, 0xd0, 0xdf, 0x96, 0x94,
+	0x0b, 0xd4, 0x85, 0xc6, 0x72, 0x99, 0x26, 0xf7, 0xb4, 0xeb, 0xe5, 0xfc, 0xa5, 0xd9, 0xb5, 0x4c,
+	0xd1, 0x39, 0x3f, 0x5a, 0xa7, 0x08, 0x94, 0xa3, 0xac, 0x2b, 0xce, 0x33, 0x41, 0x7f, 0x17, 0x92,
+	0xf4, 0x6e, 0x69, 0x28, 0xc8, 0x6a, 0x9e, 0x93, 0xc4, 0x6c, 0x48, 0xc3, 0x11, 0xb4, 0xe3, 0x7c,
+	0x51, 0xcc, 0xa9, 0xa0, 0x89, 0xa4, 0xb6, 0x55, 0x32, 0x2d, 0x18, 0x89, 0xa9, 0x79, 0xb0, 0xcb,
+	0x74, 0x58, 0x9a, 0x91, 0x01, 0xad, 0x84, 0x92, 0x64, 0x9e, 0x66, 0xd4, 0xd4, 0x65, 0x29, 0xaf,
+	0xa0, 0x53, 0x10, 0x71, 0x13, 0x15, 0x84, 0x91, 0x05, 0x37, 0x5b, 0x72, 0x9c, 0x4e, 0x37, 0xca,
+	0x51, 0x0f, 0x18, 0x4e, 0x88, 0xb8, 0x99, 0x48, 0x8c, 0x9c, 0x91, 0xe7, 0xdf, 0x42, 0x7f, 0xc7,
+	0xf4, 0xbf, 0x63, 0xf3, 0x03, 0x34, 0xa5, 0xac, 0x4a, 0xe0, 0x92, 0xa5, 0x15, 0xf0, 0x2b, 0x80,
+	0xb4, 0x88, 0xee, 0x28, 0x50, 0xed, 0x38, 0xde, 0xd3, 0x71, 0xeb, 0x67, 0xd0, 0xef, 0x7a, 0x73,
+	0x0a, 0x2d, 0xa9, 0xdf, 0x48, 0xe4, 0x95, 0xf8, 0xfa, 0xdb, 0x0d, 0xa4, 0xe8, 0x05, 0x80, 0x82,
+	0x5c, 0xb1, 0x7c, 0x61, 0xd6, 0xf6, 0x82, 0xac, 0xbf, 0x6b, 0xd0, 0x95, 0x7f, 0x6c, 0x94, 0x67,
+	0x57, 0xe9, 0x35, 0x7a, 0x0d, 0x87, 0x72, 0xb3, 0xc4, 0xf9, 0x3c, 0x12, 0xab, 0x42, 0xc9, 0xb4,
+	0x77, 0xfe, 0x62, 0xe7, 0x62, 0x05, 0x1f, 0x4e, 0x2a, 0x6c, 0xb8, 0x2a, 0x68, 0x49, 0xf1, 0x4d,
+	0xce, 0x85, 0x7a, 0x70, 0x79, 0x2a, 0x72, 0xa6, 0xb8, 0x93, 0x5d, 0x96, 0xd5, 0x30, 0x15, 0xb5,
+	0x21, 0xa3, 0x9e, 0xfe, 0x47, 0x54, 0x75, 0x28, 0x63, 0x5a, 0x53, 0x80, 0xf5, 0x09, 0x3d, 0x83,
+	0x27, 0xc1, 0x78, 0x16, 0xe2, 0x20, 0x0a, 0x3f, 0x4d, 0x70, 0xf4, 0x01, 0x5f, 0x4c, 0xc7, 0xa3,
+	0x77, 0x38, 0x54, 0x72, 0xdb, 0x74, 0xbd, 0x09, 0x26, 0x23, 0xa3, 0xb6, 0x6b, 0x7d, 0x1b, 0x86,
+	0x13, 0xa3, 0x6e, 0xfd, 0x08, 0xdd, 0xad, 0xc2, 0x4f, 0x00, 0x4d, 0x82, 0x71, 0x38, 0x1e, 0x8d,
+	0xbd, 0x0d, 0x9c, 0x86, 0x9e, 0xc2, 0xf1, 0x43, 0xfb, 0xd4, 0xa8, 0x59, 0x17, 0x60, 0x6c, 0x16,
+	0x2c, 0xb7, 0xe2, 0x10, 0x7a, 0xd5, 0x0b, 0x63, 0x69, 0xbc, 0xdb, 0x8a, 0x27, 0xfb, 0x1f, 0x69,
+	0xfd, 0xa5, 0x41, 0x77, 0x73, 0xd7, 0xee, 0x5f, 0xd8, 0x03, 0xd0, 0x99, 0x1a, 0xc0, 0x6a, 0x32,
+	0x1e, 0xf0, 0x6c, 0x95, 0xdb, 0x91, 0x17, 0x79, 0xc6, 0x29, 0x37, 0xeb, 0xfb, 0x67, 0xe1, 0x09,
+	0x1c, 0xa6, 0x3c, 0x4a, 0x68, 0xc1, 0x68, 0x4c, 0x4a, 0xb1, 0x34, 0xa4, 0x58, 0xfa, 0x6b, 0xa1,
+	0x97, 0xea, 0x69, 0x5b, 0xb7, 0xd0, 0x54, 0x32, 0xd9, 0x56, 0x6b, 0x17, 0x1a, 0x19, 0x59, 0x54,
+	0x93, 0x5c, 0xaa, 0x8e, 0x17, 0x24, 0x8b, 0x24, 0x40, 0xb1, 0x6b, 0x82, 0x51, 0x10, 0x46, 0x33,
+	0x11, 0xad, 0x3d, 0x0d, 0xe9, 0x41, 0x00, 0x5c, 0x10, 0x26, 0x22, 0x91, 0x2e, 0xd4, 0xfa, 0x6d,
+	0x97, 0x1a, 0xa4, 0x59, 0xa2, 0x2c, 0x07, 0x32, 0xef, 0x4b, 0x68, 0xcb, 0xbc, 0xb2, 0x91, 0x5f,
+	0xc2, 0x81, 0x94, 0x30, 0x7f, 0x38, 0xd9, 0x12, 0xf4, 0xef, 0x00, 0x3e, 0x07, 0x6f, 0x01, 0x05,
+	0x07, 0x00, 0x00,
 }
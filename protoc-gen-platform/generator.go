@@ -0,0 +1,431 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"go/format"
+	"path"
+	"strconv"
+	"strings"
+	"unicode"
+
+	"github.com/golang/protobuf/proto"
+)
+
+const defaultPlatformImport = "github.com/microplatform-io/platform"
+
+// Field numbers used to address services and methods in SourceCodeInfo paths
+const (
+	fileServicePath   = 6
+	serviceMethodPath = 2
+)
+
+// Generate produces a <name>.platform.go file for every requested file that
+// declares at least one service. The only supported parameter is
+// platform=<import path>, overriding where the platform package is imported from.
+func Generate(request *CodeGeneratorRequest) ([]*CodeGeneratorResponse_File, error) {
+	platformImport := defaultPlatformImport
+
+	for _, parameter := range strings.Split(request.GetParameter(), ",") {
+		if parameter == "" {
+			continue
+		}
+
+		keyValue := strings.SplitN(parameter, "=", 2)
+		if len(keyValue) != 2 || keyValue[0] != "platform" {
+			return nil, fmt.Errorf("unknown parameter %q", parameter)
+		}
+
+		platformImport = keyValue[1]
+	}
+
+	protoFiles := map[string]*FileDescriptorProto{}
+	for _, protoFile := range request.ProtoFile {
+		protoFiles[protoFile.GetName()] = protoFile
+	}
+
+	files := []*CodeGeneratorResponse_File{}
+
+	for _, fileName := range request.FileToGenerate {
+		protoFile, exists := protoFiles[fileName]
+		if !exists {
+			return nil, fmt.Errorf("%s: missing from the code generator request", fileName)
+		}
+
+		if len(protoFile.Service) <= 0 {
+			continue
+		}
+
+		content, err := newGenerator(protoFile, platformImport).generate()
+		if err != nil {
+			return nil, err
+		}
+
+		files = append(files, &CodeGeneratorResponse_File{
+			Name:    proto.String(strings.TrimSuffix(fileName, ".proto") + ".platform.go"),
+			Content: proto.String(content),
+		})
+	}
+
+	return files, nil
+}
+
+type generator struct {
+	buffer         bytes.Buffer
+	file           *FileDescriptorProto
+	platformImport string
+	comments       map[string]string
+}
+
+// P prints the arguments followed by a newline
+func (g *generator) P(args ...interface{}) {
+	for _, arg := range args {
+		fmt.Fprint(&g.buffer, arg)
+	}
+
+	g.buffer.WriteByte('\n')
+}
+
+func (g *generator) generate() (string, error) {
+	if g.file.GetPackage() == "" {
+		return "", fmt.Errorf("%s: a package is required to build the service uris", g.file.GetName())
+	}
+
+	for _, service := range g.file.Service {
+		for _, method := range service.Method {
+			if method.GetClientStreaming() {
+				return "", fmt.Errorf("%s: %s.%s: client streaming is not supported", g.file.GetName(), service.GetName(), method.GetName())
+			}
+
+			for _, typeName := range []string{method.GetInputType(), method.GetOutputType()} {
+				if _, err := g.goType(typeName); err != nil {
+					return "", fmt.Errorf("%s: %s.%s: %s", g.file.GetName(), service.GetName(), method.GetName(), err)
+				}
+			}
+		}
+	}
+
+	g.P("// Code generated by protoc-gen-platform. DO NOT EDIT.")
+	g.P("// source: ", g.file.GetName())
+	g.P()
+	g.P("package ", g.goPackageName())
+	g.P()
+	g.P("import (")
+	g.P(strconv.Quote("context"))
+	g.P()
+	g.P(strconv.Quote(g.platformImport))
+	g.P(")")
+	g.P()
+
+	for i, service := range g.file.Service {
+		g.generateService(i, service)
+	}
+
+	source, err := format.Source(g.buffer.Bytes())
+	if err != nil {
+		return "", fmt.Errorf("%s: failed to format the generated code: %s", g.file.GetName(), err)
+	}
+
+	return string(source), nil
+}
+
+func (g *generator) generateService(serviceIndex int, service *ServiceDescriptorProto) {
+	serviceName := service.GetName()
+	serviceComment := g.comments[commentKey(fileServicePath, serviceIndex)]
+
+	g.P("const (")
+	for _, method := range service.Method {
+		g.P(uriConstant(service, method), " = ", strconv.Quote(g.methodUri(method)))
+	}
+	g.P(")")
+	g.P()
+
+	// Server
+	g.P("// ", serviceName, "Server is the server API for the ", serviceName, " service.")
+	g.P("type ", serviceName, "Server interface {")
+	for j, method := range service.Method {
+		g.printComment(g.comments[commentKey(fileServicePath, serviceIndex, serviceMethodPath, j)])
+		g.P(g.serverSignature(method))
+	}
+	g.P("}")
+	g.P()
+
+	g.P("// Register", serviceName, "Server adds a handler to the service for every ", serviceName, " method.")
+	g.P("func Register", serviceName, "Server(service *platform.Service, server ", serviceName, "Server) {")
+	for j, method := range service.Method {
+		if j > 0 {
+			g.P()
+		}
+
+		g.generateHandler(service, method)
+	}
+	g.P("}")
+	g.P()
+
+	// Client
+	clientType := unexport(serviceName) + "Client"
+
+	g.P("// ", serviceName, "Client is the client API for the ", serviceName, " service.")
+	g.P("type ", serviceName, "Client interface {")
+	for j, method := range service.Method {
+		g.printComment(g.comments[commentKey(fileServicePath, serviceIndex, serviceMethodPath, j)])
+		g.P(g.clientSignature(method))
+	}
+	g.P("}")
+	g.P()
+	g.P("type ", clientType, " struct {")
+	g.P("router platform.Router")
+	g.P("}")
+	g.P()
+	g.P("func New", serviceName, "Client(router platform.Router) ", serviceName, "Client {")
+	g.P("return &", clientType, "{")
+	g.P("router: router,")
+	g.P("}")
+	g.P("}")
+	g.P()
+	for _, method := range service.Method {
+		g.generateClientMethod(clientType, service, method)
+	}
+
+	// Documentation
+	g.P("// ", serviceName, "Documentation describes the routes served by Register", serviceName, "Server.")
+	g.P("func ", serviceName, "Documentation() *platform.Documentation {")
+	g.P("return &platform.Documentation{")
+	if serviceComment != "" {
+		g.P("Description: platform.String(", strconv.Quote(serviceComment), "),")
+	}
+	g.P("ServiceRoutes: []*platform.ServiceRoute{")
+	for j, method := range service.Method {
+		methodComment := g.comments[commentKey(fileServicePath, serviceIndex, serviceMethodPath, j)]
+
+		g.P("{")
+		if methodComment != "" {
+			g.P("Description: platform.String(", strconv.Quote(methodComment), "),")
+		}
+		g.P("Request: &platform.Route{Uri: platform.String(", uriConstant(service, method), ")},")
+		g.P("Responses: []*platform.Route{")
+		g.P("{Uri: platform.String(", strconv.Quote(resourceUri(method.GetOutputType())), ")},")
+		g.P("{Uri: platform.String(", strconv.Quote("resource:///platform/reply/error"), ")},")
+		g.P("},")
+		if method.GetOptions().GetDeprecated() {
+			g.P("IsDeprecated: platform.Bool(true),")
+		}
+		g.P("},")
+	}
+	g.P("},")
+	g.P("}")
+	g.P("}")
+	g.P()
+}
+
+func (g *generator) serverSignature(method *MethodDescriptorProto) string {
+	inputType, _ := g.goType(method.GetInputType())
+	outputType, _ := g.goType(method.GetOutputType())
+
+	if method.GetServerStreaming() {
+		return fmt.Sprintf("%s(ctx context.Context, in *%s, send func(*%s) error) error", method.GetName(), inputType, outputType)
+	}
+
+	return fmt.Sprintf("%s(ctx context.Context, in *%s) (*%s, error)", method.GetName(), inputType, outputType)
+}
+
+func (g *generator) clientSignature(method *MethodDescriptorProto) string {
+	inputType, _ := g.goType(method.GetInputType())
+	outputType, _ := g.goType(method.GetOutputType())
+
+	if method.GetServerStreaming() {
+		return fmt.Sprintf("%s(ctx context.Context, in *%s, fn func(*%s) error) error", method.GetName(), inputType, outputType)
+	}
+
+	return fmt.Sprintf("%s(ctx context.Context, in *%s) (*%s, error)", method.GetName(), inputType, outputType)
+}
+
+func (g *generator) generateHandler(service *ServiceDescriptorProto, method *MethodDescriptorProto) {
+	inputType, _ := g.goType(method.GetInputType())
+	outputType, _ := g.goType(method.GetOutputType())
+
+	g.P("service.AddHandler(", strconv.Quote(strings.TrimPrefix(g.methodUri(method), "microservice://")), ", platform.HandlerFunc(func(responder platform.Responder, request *platform.Request) {")
+	g.P("in := &", inputType, "{}")
+	g.P("if err := platform.Unmarshal(request.GetPayload(), in); err != nil {")
+	g.P("platform.RespondWithError(responder, platform.NewPlatformError(platform.Error_INVALID_ARGUMENT, \"failed to unmarshal the request payload\"))")
+	g.P("return")
+	g.P("}")
+	g.P()
+
+	if method.GetServerStreaming() {
+		g.P("stream := platform.NewMessageStream(responder)")
+		g.P()
+		g.P("if err := server.", method.GetName(), "(platform.ResponderContext(responder), in, func(out *", outputType, ") error {")
+		g.P("return stream.Send(out)")
+		g.P("}); err != nil {")
+		g.P("stream.CloseWithError(err)")
+		g.P("return")
+		g.P("}")
+		g.P()
+		g.P("stream.Close(&", outputType, "{})")
+	} else {
+		g.P("out, err := server.", method.GetName(), "(platform.ResponderContext(responder), in)")
+		g.P("if err != nil {")
+		g.P("platform.RespondWithError(responder, err)")
+		g.P("return")
+		g.P("}")
+		g.P()
+		g.P("if out == nil {")
+		g.P("out = &", outputType, "{}")
+		g.P("}")
+		g.P()
+		g.P("platform.RespondWithMessage(responder, out, true)")
+	}
+
+	g.P("}))")
+}
+
+func (g *generator) generateClientMethod(clientType string, service *ServiceDescriptorProto, method *MethodDescriptorProto) {
+	outputType, _ := g.goType(method.GetOutputType())
+
+	g.P("func (c *", clientType, ") ", g.clientSignature(method), " {")
+	g.P("out := &", outputType, "{}")
+	g.P()
+
+	if method.GetServerStreaming() {
+		g.P("return platform.CallStreamContext(ctx, c.router, ", uriConstant(service, method), ", in, out, func() error {")
+		g.P("// out is reused for every reply, hand fn a copy it can hold on to")
+		g.P("return fn(platform.Clone(out).(*", outputType, "))")
+		g.P("})")
+	} else {
+		g.P("if err := platform.CallContext(ctx, c.router, ", uriConstant(service, method), ", in, out); err != nil {")
+		g.P("return nil, err")
+		g.P("}")
+		g.P()
+		g.P("return out, nil")
+	}
+
+	g.P("}")
+	g.P()
+}
+
+func (g *generator) printComment(comment string) {
+	if comment == "" {
+		return
+	}
+
+	for _, line := range strings.Split(comment, "\n") {
+		g.P("// ", line)
+	}
+}
+
+func (g *generator) goPackageName() string {
+	if goPackage := g.file.GetOptions().GetGoPackage(); goPackage != "" {
+		if i := strings.LastIndex(goPackage, ";"); i >= 0 {
+			return goPackage[i+1:]
+		}
+
+		return path.Base(goPackage)
+	}
+
+	return strings.Replace(g.file.GetPackage(), ".", "_", -1)
+}
+
+// Resolves a fully qualified message name, e.g. .teltech.Foobar, to the go
+// type protoc-gen-go generates for it in this package.
+func (g *generator) goType(typeName string) (string, error) {
+	packagePrefix := "." + g.file.GetPackage() + "."
+
+	if !strings.HasPrefix(typeName, packagePrefix) {
+		return "", fmt.Errorf("%s is not declared in package %s, only messages from the same package are supported", strings.TrimPrefix(typeName, "."), g.file.GetPackage())
+	}
+
+	return strings.Replace(strings.TrimPrefix(typeName, packagePrefix), ".", "_", -1), nil
+}
+
+// Maps a method onto microservice:///<package>/<verb>/<noun>, where the verb
+// is the first word of the method name and the noun is the rest of it.
+func (g *generator) methodUri(method *MethodDescriptorProto) string {
+	words := strings.SplitN(snakeCase(method.GetName()), "_", 2)
+
+	uri := "microservice:///" + strings.ToLower(strings.Replace(g.file.GetPackage(), ".", "/", -1)) + "/" + words[0]
+	if len(words) > 1 {
+		uri += "/" + words[1]
+	}
+
+	return uri
+}
+
+func newGenerator(file *FileDescriptorProto, platformImport string) *generator {
+	comments := map[string]string{}
+
+	if file.GetSourceCodeInfo() != nil {
+		for _, location := range file.GetSourceCodeInfo().Location {
+			locationPath := make([]int, len(location.Path))
+			for i, element := range location.Path {
+				locationPath[i] = int(element)
+			}
+
+			if comment := strings.TrimSpace(location.GetLeadingComments()); comment != "" {
+				comments[commentKey(locationPath...)] = comment
+			}
+		}
+	}
+
+	return &generator{
+		file:           file,
+		platformImport: platformImport,
+		comments:       comments,
+	}
+}
+
+func commentKey(path ...int) string {
+	elements := make([]string, len(path))
+	for i, element := range path {
+		elements[i] = strconv.Itoa(element)
+	}
+
+	return strings.Join(elements, ",")
+}
+
+func uriConstant(service *ServiceDescriptorProto, method *MethodDescriptorProto) string {
+	return service.GetName() + "_" + method.GetName() + "_Uri"
+}
+
+// Mirrors platform.ResourceUri, which the generated handlers reply with
+func resourceUri(typeName string) string {
+	messageName := strings.TrimPrefix(typeName, ".")
+
+	packageName := ""
+	if i := strings.LastIndex(messageName, "."); i >= 0 {
+		packageName, messageName = messageName[:i], messageName[i+1:]
+	}
+
+	return "resource:///" + strings.ToLower(strings.Replace(packageName, ".", "/", -1)) + "/reply/" + snakeCase(messageName)
+}
+
+func snakeCase(s string) string {
+	runes := []rune(s)
+	result := []rune{}
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// Start a new word on a lower to upper transition, or at the end of an acronym
+			if i > 0 && (unicode.IsLower(runes[i-1]) || (i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				result = append(result, '_')
+			}
+
+			r = unicode.ToLower(r)
+		}
+
+		result = append(result, r)
+	}
+
+	return string(result)
+}
+
+func unexport(s string) string {
+	if s == "" {
+		return s
+	}
+
+	runes := []rune(s)
+	runes[0] = unicode.ToLower(runes[0])
+
+	return string(runes)
+}
@@ -0,0 +1,178 @@
+package main
+
+import (
+	"go/ast"
+	"go/importer"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"testing"
+
+	"github.com/golang/protobuf/proto"
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func newFoobarFile() *FileDescriptorProto {
+	return &FileDescriptorProto{
+		Name:    proto.String("teltech/foobar.proto"),
+		Package: proto.String("teltech"),
+		MessageType: []*DescriptorProto{
+			{Name: proto.String("FoobarRequest")},
+			{Name: proto.String("Foobar")},
+		},
+		Service: []*ServiceDescriptorProto{
+			{
+				Name: proto.String("FoobarService"),
+				Method: []*MethodDescriptorProto{
+					{
+						Name:       proto.String("GetFoobar"),
+						InputType:  proto.String(".teltech.FoobarRequest"),
+						OutputType: proto.String(".teltech.Foobar"),
+					},
+					{
+						Name:            proto.String("ListFoobars"),
+						InputType:       proto.String(".teltech.FoobarRequest"),
+						OutputType:      proto.String(".teltech.Foobar"),
+						ServerStreaming: proto.Bool(true),
+						Options: &MethodOptions{
+							Deprecated: proto.Bool(true),
+						},
+					},
+				},
+			},
+		},
+		SourceCodeInfo: &SourceCodeInfo{
+			Location: []*SourceCodeInfo_Location{
+				{Path: []int32{6, 0}, LeadingComments: proto.String(" Serves foobars\n")},
+				{Path: []int32{6, 0, 2, 0}, LeadingComments: proto.String(" Fetches a single foobar\n")},
+			},
+		},
+	}
+}
+
+// Stands in for what protoc-gen-go generates for the messages of newFoobarFile
+const foobarMessagesSource = `package teltech
+
+type FoobarRequest struct{}
+
+func (*FoobarRequest) Reset()         {}
+func (*FoobarRequest) String() string { return "" }
+func (*FoobarRequest) ProtoMessage()  {}
+
+type Foobar struct{}
+
+func (*Foobar) Reset()         {}
+func (*Foobar) String() string { return "" }
+func (*Foobar) ProtoMessage()  {}
+`
+
+// Type checks the generated file against the platform package, along with
+// stand-ins for the messages it refers to.
+func typeCheck(name, content string) error {
+	fset := token.NewFileSet()
+
+	generated, err := parser.ParseFile(fset, name, content, parser.ParseComments)
+	if err != nil {
+		return err
+	}
+
+	messages, err := parser.ParseFile(fset, "teltech/foobar.pb.go", foobarMessagesSource, 0)
+	if err != nil {
+		return err
+	}
+
+	config := &types.Config{
+		Importer: importer.ForCompiler(fset, "source", nil),
+	}
+
+	_, err = config.Check("teltech", fset, []*ast.File{generated, messages}, nil)
+
+	return err
+}
+
+func TestGenerate(t *testing.T) {
+	Convey("Generating code for a file with a service should produce valid go", t, func() {
+		requestBytes, err := proto.Marshal(&CodeGeneratorRequest{
+			FileToGenerate: []string{"teltech/foobar.proto"},
+			ProtoFile:      []*FileDescriptorProto{newFoobarFile()},
+		})
+		So(err, ShouldBeNil)
+
+		request := &CodeGeneratorRequest{}
+		So(proto.Unmarshal(requestBytes, request), ShouldBeNil)
+
+		files, err := Generate(request)
+		So(err, ShouldBeNil)
+		So(files, ShouldHaveLength, 1)
+		So(files[0].GetName(), ShouldEqual, "teltech/foobar.platform.go")
+
+		content := files[0].GetContent()
+
+		So(typeCheck(files[0].GetName(), content), ShouldBeNil)
+
+		So(content, ShouldContainSubstring, "package teltech")
+		So(content, ShouldContainSubstring, `FoobarService_GetFoobar_Uri   = "microservice:///teltech/get/foobar"`)
+		So(content, ShouldContainSubstring, `FoobarService_ListFoobars_Uri = "microservice:///teltech/list/foobars"`)
+		So(content, ShouldContainSubstring, "GetFoobar(ctx context.Context, in *FoobarRequest) (*Foobar, error)")
+		So(content, ShouldContainSubstring, "ListFoobars(ctx context.Context, in *FoobarRequest, send func(*Foobar) error) error")
+		So(content, ShouldContainSubstring, `service.AddHandler("/teltech/get/foobar"`)
+		So(content, ShouldContainSubstring, "func NewFoobarServiceClient(router platform.Router) FoobarServiceClient")
+		So(content, ShouldContainSubstring, `"resource:///teltech/reply/foobar"`)
+		So(content, ShouldContainSubstring, `Description: platform.String("Fetches a single foobar")`)
+		So(content, ShouldContainSubstring, "IsDeprecated: platform.Bool(true)")
+	})
+
+	Convey("Files without services should not produce any output", t, func() {
+		file := newFoobarFile()
+		file.Service = nil
+
+		files, err := Generate(&CodeGeneratorRequest{
+			FileToGenerate: []string{"teltech/foobar.proto"},
+			ProtoFile:      []*FileDescriptorProto{file},
+		})
+		So(err, ShouldBeNil)
+		So(files, ShouldBeEmpty)
+	})
+
+	Convey("Messages from other packages should be rejected", t, func() {
+		file := newFoobarFile()
+		file.Service[0].Method[0].OutputType = proto.String(".google.protobuf.Empty")
+
+		_, err := Generate(&CodeGeneratorRequest{
+			FileToGenerate: []string{"teltech/foobar.proto"},
+			ProtoFile:      []*FileDescriptorProto{file},
+		})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("Client streaming methods should be rejected", t, func() {
+		file := newFoobarFile()
+		file.Service[0].Method[1].ClientStreaming = proto.Bool(true)
+
+		_, err := Generate(&CodeGeneratorRequest{
+			FileToGenerate: []string{"teltech/foobar.proto"},
+			ProtoFile:      []*FileDescriptorProto{file},
+		})
+		So(err, ShouldNotBeNil)
+	})
+
+	Convey("The platform import path should be configurable", t, func() {
+		files, err := Generate(&CodeGeneratorRequest{
+			FileToGenerate: []string{"teltech/foobar.proto"},
+			Parameter:      proto.String("platform=example.com/platform"),
+			ProtoFile:      []*FileDescriptorProto{newFoobarFile()},
+		})
+		So(err, ShouldBeNil)
+		So(files[0].GetContent(), ShouldContainSubstring, `"example.com/platform"`)
+	})
+}
+
+func TestMethodUri(t *testing.T) {
+	Convey("Method names should map onto verb and noun uris", t, func() {
+		g := newGenerator(&FileDescriptorProto{Package: proto.String("teltech.billing")}, defaultPlatformImport)
+
+		So(g.methodUri(&MethodDescriptorProto{Name: proto.String("GetFoobar")}), ShouldEqual, "microservice:///teltech/billing/get/foobar")
+		So(g.methodUri(&MethodDescriptorProto{Name: proto.String("CreateUserAccount")}), ShouldEqual, "microservice:///teltech/billing/create/user_account")
+		So(g.methodUri(&MethodDescriptorProto{Name: proto.String("Ping")}), ShouldEqual, "microservice:///teltech/billing/ping")
+	})
+}
@@ -0,0 +1,55 @@
+// protoc-gen-platform generates typed platform servers, clients and
+// documentation from the services declared in .proto files.
+//
+//	protoc --platform_out=. --go_out=. teltech/foobar.proto
+//
+// Every rpc is mapped onto a microservice:///<package>/<verb>/<noun> uri, e.g.
+// GetFoobar in package teltech becomes microservice:///teltech/get/foobar, and
+// replies with the resource uri of its output message. The generated code
+// lives next to the protoc-gen-go output as <name>.platform.go.
+package main
+
+import (
+	"io/ioutil"
+	"os"
+
+	"github.com/golang/protobuf/proto"
+	"github.com/microplatform-io/platform"
+)
+
+var logger = platform.GetLogger("protoc-gen-platform")
+
+func init() {
+	// protoc reads the code generator response from stdout
+	logger.Out = os.Stderr
+}
+
+func main() {
+	requestBytes, err := ioutil.ReadAll(os.Stdin)
+	if err != nil {
+		logger.Fatalf("failed to read the code generator request: %s", err)
+	}
+
+	request := &CodeGeneratorRequest{}
+	if err := proto.Unmarshal(requestBytes, request); err != nil {
+		logger.Fatalf("failed to unmarshal the code generator request: %s", err)
+	}
+
+	response := &CodeGeneratorResponse{}
+
+	files, err := Generate(request)
+	if err != nil {
+		response.Error = proto.String(err.Error())
+	} else {
+		response.File = files
+	}
+
+	responseBytes, err := proto.Marshal(response)
+	if err != nil {
+		logger.Fatalf("failed to marshal the code generator response: %s", err)
+	}
+
+	if _, err := os.Stdout.Write(responseBytes); err != nil {
+		logger.Fatalf("failed to write the code generator response: %s", err)
+	}
+}
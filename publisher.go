@@ -2,6 +2,10 @@ package platform
 
 import "errors"
 
+// Unroutable is returned by publishers that can tell when a publish did not
+// match any binding, meaning nobody will ever receive it.
+var Unroutable = errors.New("publish could not be routed to any queue")
+
 type Publisher interface {
 	Publish(topic string, body []byte) error
 }
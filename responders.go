@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"context"
 	"github.com/Sirupsen/logrus"
 	"sync"
 	"time"
@@ -75,14 +76,22 @@ type RequestResponder struct {
 	parent    Responder
 	request   *Request
 	completed bool
+	cancelled bool
 	quit      chan bool
 	mu        sync.Mutex
+
+	ctx    context.Context
+	cancel context.CancelFunc
 }
 
 func (rs *RequestResponder) Respond(response *Request) error {
 	rs.mu.Lock()
 	defer rs.mu.Unlock()
 
+	if rs.cancelled {
+		return errors.New("request responder has been cancelled")
+	}
+
 	if rs.completed {
 		return errors.New("request responder has already been completed")
 	}
@@ -92,14 +101,58 @@ func (rs *RequestResponder) Respond(response *Request) error {
 	if response.GetCompleted() {
 		rs.completed = true
 		close(rs.quit)
+		rs.cancel()
 	}
 
 	return rs.parent.Respond(response)
 }
 
+// Cancel stops the heartbeats and marks the context as done without sending
+// anything back, used when the caller is no longer waiting for a response.
+func (rs *RequestResponder) Cancel() {
+	rs.mu.Lock()
+	defer rs.mu.Unlock()
+
+	if rs.completed || rs.cancelled {
+		return
+	}
+
+	rs.cancelled = true
+	close(rs.quit)
+	rs.cancel()
+}
+
+// Context is done once the request has been completed, cancelled by the
+// caller or has passed its deadline, allowing long running handlers to stop
+// early.
+func (rs *RequestResponder) Context() context.Context {
+	return rs.ctx
+}
+
+// ResponderContext returns the context of the request being answered by the
+// responder, or a background context if the responder does not expose one.
+func ResponderContext(responder Responder) context.Context {
+	if contextResponder, ok := responder.(interface {
+		Context() context.Context
+	}); ok {
+		return contextResponder.Context()
+	}
+
+	return context.Background()
+}
+
 func NewRequestResponder(parent Responder, request *Request) *RequestResponder {
 	quit := make(chan bool, 1)
 
+	var ctx context.Context
+	var cancel context.CancelFunc
+
+	if deadline, exists := RequestDeadline(request); exists {
+		ctx, cancel = context.WithDeadline(context.Background(), deadline)
+	} else {
+		ctx, cancel = context.WithCancel(context.Background())
+	}
+
 	go func() {
 		heartbeatTicker := time.NewTicker(500 * time.Millisecond)
 		defer heartbeatTicker.Stop()
@@ -131,5 +184,114 @@ func NewRequestResponder(parent Responder, request *Request) *RequestResponder {
 		parent:  parent,
 		request: request,
 		quit:    quit,
+		ctx:     ctx,
+		cancel:  cancel,
+	}
+}
+
+// RespondWithMessage marshals the message and responds with it under its
+// resource uri, see ResourceUri.
+func RespondWithMessage(responder Responder, msg Message, completed bool) error {
+	response, err := newMessageResponse(msg, completed)
+	if err != nil {
+		return err
+	}
+
+	return responder.Respond(response)
+}
+
+// RespondWithError completes the request with a platform error. Errors that
+// don't wrap a *PlatformError are reported as internal errors.
+func RespondWithError(responder Responder, err error) error {
+	var platformError *PlatformError
+	if !errors.As(err, &platformError) {
+		platformError = NewPlatformError(Error_INTERNAL, err.Error())
+	}
+
+	return responder.Respond(newErrorResponse(platformError.Proto()))
+}
+
+func newMessageResponse(msg Message, completed bool) (*Request, error) {
+	payload, err := Marshal(msg)
+	if err != nil {
+		return nil, errors.Wrap(err, "failed to marshal the response message")
+	}
+
+	// An empty message still has a payload, only stream terminators go without
+	if payload == nil {
+		payload = []byte{}
+	}
+
+	return &Request{
+		Routing:   RouteToUri(ResourceUri(msg)),
+		Payload:   payload,
+		Completed: Bool(completed),
+	}, nil
+}
+
+// MessageStream sends a stream of messages through a responder. The most
+// recent message is held back so that Close can mark it as the completed
+// response, as callers stop reading once they see a completed response.
+type MessageStream struct {
+	responder Responder
+	pending   *Request
+}
+
+func (s *MessageStream) Send(msg Message) error {
+	// Marshal right away so that the sender is free to reuse the message
+	response, err := newMessageResponse(msg, false)
+	if err != nil {
+		return err
+	}
+
+	if err := s.flush(); err != nil {
+		return err
+	}
+
+	s.pending = response
+
+	return nil
+}
+
+func (s *MessageStream) flush() error {
+	if s.pending == nil {
+		return nil
+	}
+
+	pending := s.pending
+	s.pending = nil
+
+	return s.responder.Respond(pending)
+}
+
+// Close completes the stream. If nothing was ever sent it responds with a
+// completed response that has the empty message's resource but no payload at
+// all, which CallStream knows not to hand over as a message.
+func (s *MessageStream) Close(empty Message) error {
+	if s.pending == nil {
+		return s.responder.Respond(&Request{
+			Routing:   RouteToUri(ResourceUri(empty)),
+			Completed: Bool(true),
+		})
+	}
+
+	s.pending.Completed = Bool(true)
+
+	return s.flush()
+}
+
+// CloseWithError sends any held back message and completes the stream with
+// the error.
+func (s *MessageStream) CloseWithError(err error) error {
+	if flushErr := s.flush(); flushErr != nil {
+		return flushErr
+	}
+
+	return RespondWithError(s.responder, err)
+}
+
+func NewMessageStream(responder Responder) *MessageStream {
+	return &MessageStream{
+		responder: responder,
 	}
 }
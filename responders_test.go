@@ -1,6 +1,8 @@
 package platform
 
 import (
+	"errors"
+	"fmt"
 	"testing"
 	"time"
 
@@ -88,3 +90,115 @@ func TestRequestResponder(t *testing.T) {
 		So(len(mockPublisher.mockPublishes), ShouldEqual, 3)
 	})
 }
+
+func TestRequestResponderCancel(t *testing.T) {
+	Convey("Cancelling a request responder should stop the heartbeats and cancel its context", t, func() {
+		mockPublisher := newMockPublisher()
+
+		requestResponder := NewRequestResponder(NewPublishResponder(mockPublisher), &Request{
+			Routing: &Routing{
+				RouteFrom: []*Route{
+					&Route{Uri: String("router-testing")},
+				},
+			},
+		})
+
+		So(requestResponder.Context().Err(), ShouldBeNil)
+
+		requestResponder.Cancel()
+
+		So(requestResponder.Context().Err(), ShouldNotBeNil)
+		So(ResponderContext(requestResponder).Err(), ShouldNotBeNil)
+
+		time.Sleep(750 * time.Millisecond)
+		So(len(mockPublisher.mockPublishes), ShouldEqual, 0)
+
+		So(requestResponder.Respond(&Request{
+			Routing:   RouteToUri("resource:///teltech/reply/foobar"),
+			Completed: Bool(true),
+		}), ShouldNotBeNil)
+		So(len(mockPublisher.mockPublishes), ShouldEqual, 0)
+	})
+}
+
+func TestMessageStream(t *testing.T) {
+	Convey("A message stream should only mark the last message as completed", t, func() {
+		mockResponder := newMockResponder()
+
+		stream := NewMessageStream(mockResponder)
+		So(stream.Send(&Route{Uri: String("first")}), ShouldBeNil)
+		So(stream.Send(&Route{Uri: String("second")}), ShouldBeNil)
+		So(mockResponder.requests, ShouldHaveLength, 1)
+		So(stream.Close(&Route{}), ShouldBeNil)
+
+		So(mockResponder.requests, ShouldHaveLength, 2)
+		So(mockResponder.requests[0].GetRouting().GetRouteTo()[0].GetUri(), ShouldEqual, "resource:///platform/reply/route")
+		So(mockResponder.requests[0].GetCompleted(), ShouldBeFalse)
+		So(mockResponder.requests[1].GetCompleted(), ShouldBeTrue)
+
+		route := &Route{}
+		So(Unmarshal(mockResponder.requests[1].GetPayload(), route), ShouldBeNil)
+		So(route.GetUri(), ShouldEqual, "second")
+	})
+
+	Convey("Closing an empty message stream should complete it without a payload", t, func() {
+		mockResponder := newMockResponder()
+
+		So(NewMessageStream(mockResponder).Close(&Route{}), ShouldBeNil)
+		So(mockResponder.requests, ShouldHaveLength, 1)
+		So(mockResponder.requests[0].GetRouting().GetRouteTo()[0].GetUri(), ShouldEqual, "resource:///platform/reply/route")
+		So(mockResponder.requests[0].GetCompleted(), ShouldBeTrue)
+		So(mockResponder.requests[0].Payload, ShouldBeNil)
+	})
+
+	Convey("Sending an empty message should still give it a payload", t, func() {
+		mockResponder := newMockResponder()
+
+		stream := NewMessageStream(mockResponder)
+		So(stream.Send(&Route{}), ShouldBeNil)
+		So(stream.Close(&Route{}), ShouldBeNil)
+
+		So(mockResponder.requests, ShouldHaveLength, 1)
+		So(mockResponder.requests[0].Payload, ShouldNotBeNil)
+	})
+
+	Convey("Closing a message stream with an error should flush the pending message first", t, func() {
+		mockResponder := newMockResponder()
+
+		stream := NewMessageStream(mockResponder)
+		So(stream.Send(&Route{Uri: String("first")}), ShouldBeNil)
+		So(stream.CloseWithError(NewPlatformError(Error_NOT_FOUND, "gone")), ShouldBeNil)
+
+		So(mockResponder.requests, ShouldHaveLength, 2)
+		So(mockResponder.requests[0].GetCompleted(), ShouldBeFalse)
+		So(NewPlatformErrorFromResponse(mockResponder.requests[1]).Code, ShouldEqual, Error_NOT_FOUND)
+	})
+}
+
+func TestRespondWithError(t *testing.T) {
+	Convey("A wrapped platform error should keep its code and details", t, func() {
+		mockResponder := newMockResponder()
+
+		platformError := NewPlatformError(Error_NOT_FOUND, "user not found")
+		platformError.Details = map[string]string{"user_id": "42"}
+
+		So(RespondWithError(mockResponder, fmt.Errorf("failed to get the user: %w", platformError)), ShouldBeNil)
+		So(len(mockResponder.requests), ShouldEqual, 1)
+
+		responseError := &Error{}
+		So(Unmarshal(mockResponder.requests[0].GetPayload(), responseError), ShouldBeNil)
+		So(responseError.GetCode(), ShouldEqual, Error_NOT_FOUND)
+		So(NewPlatformErrorFromProto(responseError).Details, ShouldResemble, map[string]string{"user_id": "42"})
+	})
+
+	Convey("Any other error should be reported as an internal error", t, func() {
+		mockResponder := newMockResponder()
+
+		So(RespondWithError(mockResponder, errors.New("the database is down")), ShouldBeNil)
+
+		responseError := &Error{}
+		So(Unmarshal(mockResponder.requests[0].GetPayload(), responseError), ShouldBeNil)
+		So(responseError.GetCode(), ShouldEqual, Error_INTERNAL)
+		So(responseError.GetMessage(), ShouldEqual, "the database is down")
+	})
+}
@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"net/url"
@@ -14,9 +15,15 @@ import (
 
 var RequestTimeout = errors.New("Request timed out")
 
+// Router sends requests to the services handling them. Requests routed with a
+// context are cancelled once the context is done, but the services only hear
+// about it when they opt in through Service.SetCancellationSubscriber, they
+// keep working on the request otherwise.
 type Router interface {
 	Route(request *Request) (*Request, error)
+	RouteContext(ctx context.Context, request *Request) (*Request, error)
 	Stream(request *Request) (chan *Request, chan interface{})
+	StreamContext(ctx context.Context, request *Request) (chan *Request, chan interface{})
 
 	SetHeartbeatTimeout(heartbeatTimeout time.Duration)
 }
@@ -28,20 +35,28 @@ type TracingRouter struct {
 }
 
 func (r *TracingRouter) Route(request *Request) (*Request, error) {
+	return r.RouteContext(context.Background(), request)
+}
+
+func (r *TracingRouter) RouteContext(ctx context.Context, request *Request) (*Request, error) {
 	trace := r.tracer.Start(r.parentTrace, request.GetRouting().GetRouteTo()[0].GetUri())
 	defer r.tracer.End(trace)
 
 	request.Trace = trace
 
-	return r.parentRouter.Route(request)
+	return r.parentRouter.RouteContext(ctx, request)
 }
 
 func (r *TracingRouter) Stream(request *Request) (chan *Request, chan interface{}) {
+	return r.StreamContext(context.Background(), request)
+}
+
+func (r *TracingRouter) StreamContext(ctx context.Context, request *Request) (chan *Request, chan interface{}) {
 	trace := r.tracer.Start(r.parentTrace, request.GetRouting().GetRouteTo()[0].GetUri())
 
 	request.Trace = trace
 
-	internalResponses, internalTimeout := r.parentRouter.Stream(request)
+	internalResponses, internalTimeout := r.parentRouter.StreamContext(ctx, request)
 
 	returnedResponses := make(chan *Request)
 	returnedTimeout := make(chan interface{})
@@ -52,7 +67,13 @@ func (r *TracingRouter) Stream(request *Request) (chan *Request, chan interface{
 		for {
 			select {
 			case response := <-internalResponses:
-				returnedResponses <- response
+				select {
+				case returnedResponses <- response:
+				case <-ctx.Done():
+					close(returnedTimeout)
+
+					return
+				}
 
 				if response.GetCompleted() {
 					return
@@ -85,6 +106,9 @@ type StandardRouter struct {
 	subscriber       Subscriber
 	heartbeatTimeout time.Duration
 
+	// Publishes requests on the legacy microservice-<path> routing keys
+	legacyRoutingKeys bool
+
 	topic string
 
 	pendingResponses map[string]chan *Request
@@ -94,35 +118,55 @@ type StandardRouter struct {
 func createResponseChanWithError(request *Request, err *Error) chan *Request {
 	responses := make(chan *Request, 1)
 
-	errorBytes, _ := Marshal(err)
-
-	responses <- generateResponse(request, &Request{
-		Routing:   RouteToUri("resource:///platform/reply/error"),
-		Payload:   errorBytes,
-		Completed: Bool(true),
-	})
+	responses <- generateResponse(request, newErrorResponse(err))
 
 	return responses
 }
 
 func (r *StandardRouter) Route(originalRequest *Request) (*Request, error) {
-	responses, streamTimeout := r.Stream(originalRequest)
+	return r.RouteContext(context.Background(), originalRequest)
+}
+
+// RouteContext behaves like Route, but gives up as soon as the context is
+// cancelled or its deadline passes, returning the context's error.
+//
+// When the completed response is a platform error, the response is returned
+// along with a *PlatformError describing it.
+func (r *StandardRouter) RouteContext(ctx context.Context, originalRequest *Request) (*Request, error) {
+	responses, streamTimeout := r.StreamContext(ctx, originalRequest)
 
 	for {
 		select {
 		case response := <-responses:
 			if response.GetCompleted() {
+				if platformError := NewPlatformErrorFromResponse(response); platformError != nil {
+					return response, platformError
+				}
+
 				return response, nil
 			}
 		case <-streamTimeout:
+			if err := ctx.Err(); err != nil {
+				return nil, err
+			}
+
 			return nil, RequestTimeout
+		case <-ctx.Done():
+			return nil, ctx.Err()
 		}
 	}
-
-	return nil, RequestTimeout
 }
 
 func (r *StandardRouter) Stream(originalRequest *Request) (chan *Request, chan interface{}) {
+	return r.StreamContext(context.Background(), originalRequest)
+}
+
+// StreamContext behaves like Stream, but stops waiting for responses once the
+// context is done. The timeout channel is closed in that case, so callers that
+// need to distinguish a cancellation from a heartbeat timeout should inspect
+// ctx.Err(). Either way a cancellation is published for the request, which
+// only reaches services that set a cancellation subscriber.
+func (r *StandardRouter) StreamContext(ctx context.Context, originalRequest *Request) (chan *Request, chan interface{}) {
 	request := proto.Clone(originalRequest).(*Request)
 
 	if request.Uuid == nil {
@@ -143,9 +187,22 @@ func (r *StandardRouter) Stream(originalRequest *Request) (chan *Request, chan i
 	if err != nil {
 		return createResponseChanWithError(request, &Error{
 			Message: String(fmt.Sprintf("Failed to parse the RouteTo URI: %s", err)),
+			Code:    Error_INVALID_ARGUMENT.Enum(),
 		}), nil
 	}
 
+	routingKey := RoutingKey(parsedURI.Scheme, parsedURI.Path)
+	if r.getLegacyRoutingKeys() {
+		routingKey = LegacyRoutingKey(parsedURI.Scheme, parsedURI.Path)
+	}
+
+	// Only ever tighten a deadline that was already set further up the chain
+	if ctxDeadline, exists := ctx.Deadline(); exists {
+		if requestDeadline, exists := RequestDeadline(request); !exists || ctxDeadline.Before(requestDeadline) {
+			SetRequestDeadline(request, ctxDeadline)
+		}
+	}
+
 	if request.Routing != nil {
 		request.Routing.RouteFrom = append(request.Routing.RouteFrom, &Route{
 			Uri: String(r.topic),
@@ -156,12 +213,14 @@ func (r *StandardRouter) Stream(originalRequest *Request) (chan *Request, chan i
 	if err != nil {
 		return createResponseChanWithError(request, &Error{
 			Message: String(fmt.Sprintf("Failed to marshal the request: %s", err)),
+			Code:    Error_INTERNAL.Enum(),
 		}), nil
 	}
 
 	internalResponses := make(chan *Request, 5)
 	responses := make(chan *Request, 5)
 	streamTimeout := make(chan interface{})
+	publishFailed := make(chan interface{})
 
 	r.mu.Lock()
 	r.pendingResponses[requestUUID] = internalResponses
@@ -201,10 +260,20 @@ func (r *StandardRouter) Stream(originalRequest *Request) (chan *Request, chan i
 			case <-timer.C:
 				close(streamTimeout)
 
-				r.mu.Lock()
-				delete(r.pendingResponses, requestUUID)
-				r.mu.Unlock()
+				r.removePendingResponses(requestUUID)
+				r.publishCancellation(requestUUID, routingKey)
+
+				return
+
+			case <-ctx.Done():
+				close(streamTimeout)
+
+				r.removePendingResponses(requestUUID)
+				r.publishCancellation(requestUUID, routingKey)
+
+				return
 
+			case <-publishFailed:
 				return
 			}
 
@@ -212,9 +281,22 @@ func (r *StandardRouter) Stream(originalRequest *Request) (chan *Request, chan i
 		}
 	}()
 
-	if err := r.publisher.Publish(parsedURI.Scheme+"-"+parsedURI.Path, requestBytes); err != nil {
+	if err := r.publisher.Publish(routingKey, requestBytes); err != nil {
+		r.removePendingResponses(requestUUID)
+		close(publishFailed)
+
+		// Nobody is bound to the routing key, waiting for a heartbeat would be pointless
+		if err == Unroutable {
+			return createResponseChanWithError(request, &Error{
+				Message: String(fmt.Sprintf("service unavailable: no service is handling %s", requestURI)),
+				Code:    Error_UNAVAILABLE.Enum(),
+			}), nil
+		}
+
 		return createResponseChanWithError(request, &Error{
-			Message: String(fmt.Sprintf("Failed to publish request to microservices: %s", err)),
+			Message:   String(fmt.Sprintf("Failed to publish request to microservices: %s", err)),
+			Code:      Error_UNAVAILABLE.Enum(),
+			Retryable: Bool(true),
 		}), nil
 	}
 
@@ -223,10 +305,66 @@ func (r *StandardRouter) Stream(originalRequest *Request) (chan *Request, chan i
 	return responses, streamTimeout
 }
 
+func (r *StandardRouter) removePendingResponses(requestUUID string) {
+	r.mu.Lock()
+	delete(r.pendingResponses, requestUUID)
+	r.mu.Unlock()
+}
+
+// CancellationTopic is where the cancellations of the requests published on
+// the routing key go. Every instance of a service binds it on a queue of its
+// own, since there is no telling which one is handling the request.
+func CancellationTopic(routingKey string) string {
+	return "cancellation." + routingKey
+}
+
+// Lets the service handling the request know that nobody is waiting for its
+// responses anymore, so that it may stop working on it.
+func (r *StandardRouter) publishCancellation(requestUUID, routingKey string) {
+	cancellationBytes, err := Marshal(&Request{
+		Uuid:      String(requestUUID),
+		Routing:   RouteToUri("resource:///platform/cancel"),
+		Completed: Bool(true),
+	})
+	if err != nil {
+		logger.Errorf("[StandardRouter.publishCancellation] %s - failed to marshal the cancellation: %s", requestUUID, err)
+		return
+	}
+
+	err = r.publisher.Publish(CancellationTopic(routingKey), cancellationBytes)
+
+	// Only services that set a cancellation subscriber bind the cancellations
+	if err == Unroutable {
+		logger.Debugf("[StandardRouter.publishCancellation] %s - no service is listening for the cancellation", requestUUID)
+		return
+	}
+
+	if err != nil {
+		logger.Errorf("[StandardRouter.publishCancellation] %s - failed to publish the cancellation: %s", requestUUID, err)
+	}
+}
+
 func (r *StandardRouter) SetHeartbeatTimeout(heartbeatTimeout time.Duration) {
 	r.heartbeatTimeout = heartbeatTimeout
 }
 
+// SetLegacyRoutingKeys makes the router publish requests on the legacy
+// microservice-<path> routing keys instead of the dotted ones, for as long as
+// it has to reach services that predate path templates and only bind those.
+// Requests for handlers with templated paths can't be routed while it is set.
+func (r *StandardRouter) SetLegacyRoutingKeys(legacyRoutingKeys bool) {
+	r.mu.Lock()
+	r.legacyRoutingKeys = legacyRoutingKeys
+	r.mu.Unlock()
+}
+
+func (r *StandardRouter) getLegacyRoutingKeys() bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.legacyRoutingKeys
+}
+
 func (r *StandardRouter) subscribe() {
 	r.subscriber.Subscribe(r.topic, ConsumerHandlerFunc(func(body []byte) error {
 		response := &Request{}
@@ -240,16 +378,20 @@ func (r *StandardRouter) subscribe() {
 			responseUri = response.GetRouting().GetRouteTo()[0].GetUri()
 		}
 
+		// The response belongs to the stream once it has been handed over, so
+		// don't touch it past that point
+		responseCompleted := response.GetCompleted()
+
 		r.mu.Lock()
-		if responses, exists := r.pendingResponses[response.GetUuid()]; exists {
+		if responses, exists := r.pendingResponses[responseUuid]; exists {
 			select {
 			case responses <- response:
 			default:
 				logger.Printf("[StandardRouter.Subscriber] %s - %s - reply chan was not available", responseUuid, responseUri)
 			}
 
-			if response.GetCompleted() {
-				delete(r.pendingResponses, response.GetUuid())
+			if responseCompleted {
+				delete(r.pendingResponses, responseUuid)
 			}
 		} else {
 			logger.Errorf("[StandardRouter.Subscriber] %s - %s - pending response channel did not exist, it may have been deleted", responseUuid, responseUri)
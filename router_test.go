@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"context"
 	"strings"
 	"testing"
 	"time"
@@ -138,4 +139,169 @@ func TestNewStandardRouter(t *testing.T) {
 		So(actualUuid, ShouldBeEmpty)
 		So(pendingResponses, ShouldBeNil)
 	})
+
+	Convey("Verify that timeouts publish a cancellation to the service", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+
+		requestUuid := CreateUUID()
+
+		router := NewStandardRouterWithTopic(mockPublisher, mockSubscriber, "testing-router")
+		router.SetHeartbeatTimeout(10 * time.Millisecond)
+
+		_, timeout := router.Stream(&Request{
+			Uuid:    String(requestUuid),
+			Routing: RouteToUri("microservice:///teltech/get/foobar"),
+		})
+
+		actualUuid, _ := getStandardRouterPendingResponsesMatchingUuidPrefix(router, requestUuid)
+
+		<-timeout
+
+		// Give the stream goroutine a moment to publish the cancellation
+		time.Sleep(10 * time.Millisecond)
+
+		So(len(mockPublisher.mockPublishes), ShouldEqual, 2)
+		So(mockPublisher.mockPublishes[1].topic, ShouldEqual, "cancellation.microservice.teltech.get.foobar")
+
+		cancellation := &Request{}
+		So(Unmarshal(mockPublisher.mockPublishes[1].body, cancellation), ShouldBeNil)
+		So(cancellation.GetUuid(), ShouldEqual, actualUuid)
+		So(IsCancellationRequest(cancellation), ShouldBeTrue)
+	})
+}
+
+func TestStandardRouterContext(t *testing.T) {
+	Convey("Cancelling the context should cleanup the pending responses and close the timeout", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+
+		requestUuid := CreateUUID()
+
+		router := NewStandardRouterWithTopic(mockPublisher, mockSubscriber, "testing-router")
+		router.SetHeartbeatTimeout(time.Second)
+
+		ctx, cancel := context.WithCancel(context.Background())
+
+		responses, timeout := router.StreamContext(ctx, &Request{
+			Uuid:    String(requestUuid),
+			Routing: RouteToUri("microservice:///teltech/get/foobar"),
+		})
+
+		actualUuid, pendingResponses := getStandardRouterPendingResponsesMatchingUuidPrefix(router, requestUuid)
+		So(actualUuid, ShouldNotBeEmpty)
+		So(pendingResponses, ShouldNotBeNil)
+
+		cancel()
+
+		select {
+		case <-responses:
+			t.Error("We were not expecting a response")
+		case <-timeout:
+		case <-time.After(100 * time.Millisecond):
+			t.Error("The timeout was not closed after cancelling the context")
+		}
+
+		actualUuid, pendingResponses = getStandardRouterPendingResponsesMatchingUuidPrefix(router, requestUuid)
+		So(actualUuid, ShouldBeEmpty)
+		So(pendingResponses, ShouldBeNil)
+	})
+
+	Convey("Routing with a deadline should return the context error once the deadline passes", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+
+		router := NewStandardRouterWithTopic(mockPublisher, mockSubscriber, "testing-router")
+		router.SetHeartbeatTimeout(time.Second)
+
+		ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+		defer cancel()
+
+		startedAt := time.Now()
+
+		response, err := router.RouteContext(ctx, &Request{
+			Routing: RouteToUri("microservice:///teltech/get/foobar"),
+		})
+		So(response, ShouldBeNil)
+		So(err, ShouldResemble, context.DeadlineExceeded)
+		So(time.Since(startedAt), ShouldBeLessThan, time.Second)
+
+		// Give the stream goroutine a moment to observe the cancellation
+		time.Sleep(10 * time.Millisecond)
+
+		router.mu.Lock()
+		So(len(router.pendingResponses), ShouldEqual, 0)
+		router.mu.Unlock()
+	})
+
+	Convey("Streaming with a deadline should set the deadline on the published request", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+
+		router := NewStandardRouterWithTopic(mockPublisher, mockSubscriber, "testing-router")
+		router.SetHeartbeatTimeout(10 * time.Millisecond)
+
+		deadline := time.Now().Add(time.Minute)
+
+		ctx, cancel := context.WithDeadline(context.Background(), deadline)
+		defer cancel()
+
+		router.StreamContext(ctx, &Request{
+			Routing: RouteToUri("microservice:///teltech/get/foobar"),
+		})
+
+		So(len(mockPublisher.mockPublishes), ShouldEqual, 1)
+
+		publishedRequest := &Request{}
+		So(Unmarshal(mockPublisher.mockPublishes[0].body, publishedRequest), ShouldBeNil)
+
+		publishedDeadline, exists := RequestDeadline(publishedRequest)
+		So(exists, ShouldBeTrue)
+		So(publishedDeadline.Equal(deadline), ShouldBeTrue)
+	})
+
+	Convey("A timeout that is not caused by the context should still return a request timeout", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+
+		router := NewStandardRouterWithTopic(mockPublisher, mockSubscriber, "testing-router")
+		router.SetHeartbeatTimeout(10 * time.Millisecond)
+
+		response, err := router.RouteContext(context.Background(), &Request{
+			Routing: RouteToUri("microservice:///teltech/get/foobar"),
+		})
+		So(response, ShouldBeNil)
+		So(err, ShouldResemble, RequestTimeout)
+	})
+}
+
+type unroutablePublisher struct{}
+
+func (p *unroutablePublisher) Publish(topic string, body []byte) error {
+	return Unroutable
+}
+
+func TestStandardRouterUnroutable(t *testing.T) {
+	Convey("An unroutable request should immediately get a service unavailable error", t, func() {
+		router := NewStandardRouterWithTopic(&unroutablePublisher{}, newMockSubscriber(), "testing-router")
+		router.SetHeartbeatTimeout(time.Second)
+
+		startedAt := time.Now()
+
+		response, err := router.Route(&Request{
+			Uuid:    String(CreateUUID()),
+			Routing: RouteToUri("microservice:///teltech/get/foobar"),
+		})
+		So(time.Since(startedAt), ShouldBeLessThan, 100*time.Millisecond)
+
+		platformError, ok := err.(*PlatformError)
+		So(ok, ShouldBeTrue)
+		So(platformError.Code, ShouldEqual, Error_UNAVAILABLE)
+		So(platformError.Message, ShouldContainSubstring, "service unavailable")
+		So(IsErrorResponse(response), ShouldBeTrue)
+
+		router.mu.Lock()
+		So(router.pendingResponses, ShouldBeEmpty)
+		router.mu.Unlock()
+	})
 }
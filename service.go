@@ -1,9 +1,11 @@
 package platform
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"github.com/Sirupsen/logrus"
+	"net/url"
 	"os"
 	"os/signal"
 	"runtime"
@@ -14,6 +16,20 @@ import (
 	"time"
 )
 
+// How long Service.Close waits for a draining subscriber to finish handling
+// the messages it already received
+var SHUTDOWN_TIMEOUT = 30 * time.Second
+
+// The topics that listeners publish the bodies they couldn't handle to, each
+// followed by the topic of the listener
+const (
+	// Publishes whose listener panicked
+	PanicListenerTopicPrefix = "panic.listener."
+
+	// Publishes an event listener couldn't decode
+	DeadLetterListenerTopicPrefix = "deadletter.listener."
+)
+
 type Handler interface {
 	ServePlatform(responder Responder, request *Request)
 }
@@ -72,14 +88,32 @@ type Service struct {
 	healthManager  HealthManager
 	healthCheckers []HealthChecker
 
+	middlewares         []Middleware
+	listenerMiddlewares []ListenerMiddleware
+
+	inflightRequests   map[string]*RequestResponder
+	inflightRequestsMu sync.Mutex
+
+	// The paths of every handler and the routing keys they're bound to, in the
+	// order they were added
+	pathTemplates   []*PathTemplate
+	routingKeys     [][]string
+	pathTemplatesMu sync.Mutex
+
+	// Receives the cancellations on a queue of this instance's own, see
+	// SetCancellationSubscriber
+	cancellationSubscriber Subscriber
+
 	mu                sync.Mutex
 	closed            bool
 	workerPendingJobs int32
 	workerQuitChan    chan interface{}
-	allWorkersDone    chan interface{}
+
+	allWorkersDone     chan interface{}
+	allWorkersDoneOnce sync.Once
 }
 
-func (s *Service) generateResponder(request *Request, path string) Responder {
+func (s *Service) generateResponder(request *Request, path string) *RequestResponder {
 	responder := s.responder
 
 	if s.tracer != nil {
@@ -93,59 +127,243 @@ func (s *Service) generateResponder(request *Request, path string) Responder {
 	return NewRequestResponder(responder, request)
 }
 
-func (s *Service) AddHandler(path string, handler Handler) {
+// Use adds middleware to every handler added afterwards, the first middleware
+// being the outermost one.
+func (s *Service) Use(middlewares ...Middleware) {
+	s.middlewares = append(s.middlewares, middlewares...)
+}
+
+func (s *Service) AddHandler(path string, handler Handler, options ...HandlerOption) {
 	logger.Infoln("[Service.AddHandler] adding handler", path)
 
-	s.subscriber.Subscribe("microservice-"+path, ConsumerHandlerFunc(func(body []byte) error {
-		// TODO: This error is ignored at the subscriber level, so therefore this message is permanently lost!
-		if !s.canAcceptWork() {
-			return errors.New("no new work can be accepted")
+	handlerOptions := &handlerOptions{}
+	for _, option := range options {
+		option(handlerOptions)
+	}
+
+	pathTemplate, err := ParsePathTemplate(path)
+	if err != nil {
+		panic(fmt.Sprintf("invalid handler path: %s", err))
+	}
+
+	middlewares := append(append([]Middleware{}, s.middlewares...), handlerOptions.middlewares...)
+	handler = chainMiddleware(handler, middlewares...)
+
+	// Recovery sits outside of every other middleware so that their panics are caught as well
+	handler = RecoverMiddleware(path, func(request *Request, r interface{}) {
+		body, err := Marshal(request)
+		if err != nil {
+			logger.Errorf("[Service.AddHandler] %s - failed to marshal the request that panicked: %s", path, err)
+			return
 		}
 
-		s.incrementWorkerPendingJobs()
-		defer s.decrementWorkerPendingJobs()
+		s.publisher.Publish("panic.handler."+path, body)
+	})(handler)
 
+	consumerHandler := ConsumerHandlerFunc(func(body []byte) error {
 		request := &Request{}
 		if err := Unmarshal(body, request); err != nil {
 			return nil
 		}
 
-		responder := s.generateResponder(request, path)
+		if parsedURI, err := url.Parse(requestUri(request)); err == nil {
+			if pathParams, matches := pathTemplate.Match(parsedURI.Path); matches {
+				// The request reaches every handler whose binding matches it, only the one
+				// with the most specific path serves it
+				if !s.servesPath(pathTemplate, parsedURI.Path) {
+					return nil
+				}
 
-		defer capturePanic(func(r interface{}) {
-			logger.WithFields(logrus.Fields{
-				"resource_type": "handler",
-				"path":          path,
-				"reason":        r,
-			}).Error("Service has panicked!")
+				if len(pathParams) > 0 {
+					request.PathParams = pathParams
+				}
+			}
+		}
+
+		// Counted before checking, so that Close can't miss a job that got past the check
+		s.incrementWorkerPendingJobs()
+		defer s.decrementWorkerPendingJobs()
+
+		// Subscribers that settle deliveries after handling them requeue the message for
+		// another instance, anything else drops it
+		if !s.canAcceptWork() {
+			return errors.New("no new work can be accepted")
+		}
 
-			panicErrorBytes, _ := Marshal(&Error{
-				Message: String(fmt.Sprintf("A fatal error has occurred. %s: %s %s", path, identifyPanic(), r)),
-			})
+		// The caller has already given up on this request, so don't bother doing the work
+		if deadline, exists := RequestDeadline(request); exists && time.Now().After(deadline) {
+			s.respondDeadlineExceeded(request, path, deadline)
+			return nil
+		}
 
-			responder.Respond(&Request{
-				Routing:   RouteToUri("resource:///platform/reply/error"),
-				Payload:   panicErrorBytes,
-				Completed: Bool(true),
-			})
+		responder := s.generateResponder(request, path)
 
-			s.publisher.Publish("panic.handler."+path, body)
-		})
+		s.trackInflightRequest(request.GetUuid(), responder)
+		defer s.untrackInflightRequest(request.GetUuid())
 
 		handler.ServePlatform(responder, request)
 
 		return nil
-	}))
+	})
+
+	// Exact paths are bound on their legacy key as well, for the routers that
+	// still publish it, while sharing a single pool of workers
+	routingKeys := []string{pathTemplate.RoutingKey("microservice")}
+	if pathTemplate.IsExact() {
+		routingKeys = append(routingKeys, LegacyRoutingKey("microservice", path))
+	}
+
+	s.pathTemplatesMu.Lock()
+	s.pathTemplates = append(s.pathTemplates, pathTemplate)
+	s.routingKeys = append(s.routingKeys, routingKeys)
+	cancellationSubscriber := s.cancellationSubscriber
+	s.pathTemplatesMu.Unlock()
+
+	if cancellationSubscriber != nil {
+		s.subscribeCancellations(cancellationSubscriber, routingKeys)
+	}
+
+	subscribeOptions := append([]SubscribeOption{WithAdditionalTopics(routingKeys[1:]...)}, handlerOptions.subscribeOptions...)
+
+	s.subscriber.Subscribe(routingKeys[0], consumerHandler, subscribeOptions...)
+}
+
+// SetCancellationSubscriber has the service receive the cancellations routers
+// publish for the requests their callers gave up on, cancelling the context of
+// the handlers still working on them. Requests are shared across every instance
+// consuming the service's queue, so the subscriber has to consume a queue of
+// this instance's own, such as an exclusive one, for every instance to get the
+// cancellations.
+func (s *Service) SetCancellationSubscriber(subscriber Subscriber) {
+	s.pathTemplatesMu.Lock()
+	s.cancellationSubscriber = subscriber
+	routingKeys := append([][]string{}, s.routingKeys...)
+	s.pathTemplatesMu.Unlock()
+
+	for i := range routingKeys {
+		s.subscribeCancellations(subscriber, routingKeys[i])
+	}
+}
+
+func (s *Service) subscribeCancellations(subscriber Subscriber, routingKeys []string) {
+	topics := []string{}
+	for _, routingKey := range routingKeys {
+		topics = append(topics, CancellationTopic(routingKey))
+	}
+
+	subscriber.Subscribe(topics[0], ConsumerHandlerFunc(func(body []byte) error {
+		request := &Request{}
+		if err := Unmarshal(body, request); err != nil {
+			return nil
+		}
+
+		// Cancellations are handled even while closing, they only help us finish faster
+		if IsCancellationRequest(request) {
+			s.cancelInflightRequest(request.GetUuid())
+		}
+
+		return nil
+	}), WithAdditionalTopics(topics[1:]...))
+}
+
+// Whether the handler with the template serves the path, rather than one with a
+// more specific template. Handlers with templates that are just as specific are
+// served in the order they were added.
+func (s *Service) servesPath(pathTemplate *PathTemplate, path string) bool {
+	s.pathTemplatesMu.Lock()
+	defer s.pathTemplatesMu.Unlock()
+
+	addedBefore := true
+
+	for _, other := range s.pathTemplates {
+		if other == pathTemplate {
+			addedBefore = false
+			continue
+		}
+
+		if _, matches := other.Match(path); !matches {
+			continue
+		}
+
+		if other.MoreSpecificThan(pathTemplate) || (addedBefore && !pathTemplate.MoreSpecificThan(other)) {
+			return false
+		}
+	}
+
+	return true
+}
+
+func (s *Service) respondDeadlineExceeded(request *Request, path string, deadline time.Time) {
+	logger.WithFields(logrus.Fields{
+		"request_uuid": request.GetUuid(),
+		"path":         path,
+		"deadline":     request.GetDeadline(),
+	}).Warn("Request deadline has been exceeded")
+
+	s.responder.Respond(generateResponse(request, newErrorResponse(&Error{
+		Message: String(fmt.Sprintf("deadline exceeded: %s expired %s ago", path, time.Since(deadline))),
+		Code:    Error_DEADLINE_EXCEEDED.Enum(),
+	})))
+}
+
+func (s *Service) trackInflightRequest(requestUuid string, responder *RequestResponder) {
+	s.inflightRequestsMu.Lock()
+	s.inflightRequests[requestUuid] = responder
+	s.inflightRequestsMu.Unlock()
+}
+
+func (s *Service) untrackInflightRequest(requestUuid string) {
+	s.inflightRequestsMu.Lock()
+	delete(s.inflightRequests, requestUuid)
+	s.inflightRequestsMu.Unlock()
+}
+
+// Every instance gets the cancellation, only the one handling the request has
+// something to cancel.
+func (s *Service) cancelInflightRequest(requestUuid string) {
+	s.inflightRequestsMu.Lock()
+	responder, exists := s.inflightRequests[requestUuid]
+	s.inflightRequestsMu.Unlock()
+
+	if !exists {
+		logger.Debugf("[Service.cancelInflightRequest] %s - request is not in flight on this instance", requestUuid)
+		return
+	}
+
+	logger.Infof("[Service.cancelInflightRequest] %s - cancelling request", requestUuid)
+
+	responder.Cancel()
+}
+
+// WorkerPools reports how the worker pools of the service's subscriber are
+// sized, if the subscriber can tell.
+func (s *Service) WorkerPools() []WorkerPool {
+	if reporter, ok := s.subscriber.(WorkerPoolReporter); ok {
+		return reporter.WorkerPools()
+	}
+
+	return []WorkerPool{}
 }
 
 func (s *Service) AddHealthChecker(healthChecker HealthChecker) {
 	s.healthCheckers = append(s.healthCheckers, healthChecker)
 }
 
-func (s *Service) AddListener(topic string, handler ConsumerHandler) {
+// AddListener subscribes the handler to the topic behind the listener
+// middleware. Handlers that implement MetadataConsumerHandler are handed the
+// delivery's metadata, such as its message id and the routing key it matched.
+func (s *Service) AddListener(topic string, handler ConsumerHandler, options ...ListenerOption) {
 	logger.Infoln("[Service.AddListener] Adding listener", topic)
 
-	s.subscriber.Subscribe(topic, ConsumerHandlerFunc(func(body []byte) error {
+	listenerOptions := &listenerOptions{}
+	for _, option := range options {
+		option(listenerOptions)
+	}
+
+	middlewares := append(append([]ListenerMiddleware{}, s.listenerMiddlewares...), listenerOptions.middlewares...)
+	handler = chainListenerMiddleware(topic, handler, middlewares...)
+
+	s.subscriber.Subscribe(topic, MetadataConsumerHandlerFunc(func(body []byte, metadata *MessageMetadata) error {
 		s.incrementWorkerPendingJobs()
 		defer s.decrementWorkerPendingJobs()
 
@@ -156,13 +374,44 @@ func (s *Service) AddListener(topic string, handler ConsumerHandler) {
 				"reason":        r,
 			}).Error("Service has panicked!")
 
-			s.publisher.Publish("panic.listener."+topic, body)
+			s.publisher.Publish(PanicListenerTopicPrefix+topic, body)
 		})
 
 		logger.Infof("[Service.AddListener] Handling %s publish", topic)
 
-		return handler.HandleMessage(body)
-	}))
+		return HandleMessageWithMetadata(handler, body, metadata)
+	}), listenerOptions.subscribeOptions...)
+}
+
+// UseListener adds middleware to every listener added afterwards, the first
+// middleware being the outermost one.
+func (s *Service) UseListener(middlewares ...ListenerMiddleware) {
+	s.listenerMiddlewares = append(s.listenerMiddlewares, middlewares...)
+}
+
+// AddEventListener decodes every publish on the topic into a new message of
+// the prototype's type before handing it to the handler. Publishes that can't
+// be decoded are sent to DeadLetterListenerTopicPrefix+topic rather than
+// retried.
+func (s *Service) AddEventListener(topic string, prototype Message, handler EventHandler, options ...ListenerOption) {
+	s.AddListener(topic, ConsumerHandlerFunc(func(body []byte) error {
+		event := newMessageLike(prototype)
+
+		if err := Unmarshal(body, event); err != nil {
+			logger.WithFields(logrus.Fields{
+				"topic": topic,
+				"error": err.Error(),
+			}).Error("failed to decode the event, dead lettering it")
+
+			if err := s.publisher.Publish(DeadLetterListenerTopicPrefix+topic, body); err != nil {
+				return fmt.Errorf("failed to dead letter the undecodable event: %s", err)
+			}
+
+			return nil
+		}
+
+		return handler.HandleEvent(event)
+	}), options...)
 }
 
 func (s *Service) canAcceptWork() bool {
@@ -174,46 +423,86 @@ func (s *Service) canAcceptWork() bool {
 	}
 }
 
+// Close waits for the work in flight before returning. A subscriber that can
+// be drained stops receiving messages first and gets up to SHUTDOWN_TIMEOUT to
+// finish handling the ones it has. Whether the messages still unhandled by then
+// go back to the broker is up to the subscriber, the AMQP one only requeues them
+// in at-least-once mode since it acks them on dispatch otherwise.
 func (s *Service) Close() error {
 	s.mu.Lock()
-	defer s.mu.Unlock()
+	closed := s.closed
+	s.closed = true
+	s.mu.Unlock()
 
-	if !s.closed {
-		logger.Infoln("[Service.Close] service is shutting down")
+	if closed {
+		logger.Infoln("[Service.Close] service is already shutting down")
 
-		s.closed = true
+		<-s.allWorkersDone
 
-		close(s.workerQuitChan)
+		return nil
+	}
+
+	logger.Infoln("[Service.Close] service is shutting down")
 
-		logger.Infof("[Service.Close] pending jobs: %d", s.workerPendingJobs)
+	if subscriber, ok := s.subscriber.(DrainingSubscriber); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
 
-		if s.workerPendingJobs > 0 {
-			<-s.allWorkersDone
-		} else {
-			close(s.allWorkersDone)
+		if err := subscriber.Close(ctx); err != nil {
+			logger.Warnf("[Service.Close] failed to drain the subscriber: %s", err)
 		}
 
-		logger.Infoln("[Service.Close] all workers have finished")
-	} else {
-		logger.Infoln("[Service.Close] service has already been shut down")
+		cancel()
+	}
+
+	close(s.workerQuitChan)
+
+	pendingJobs := atomic.LoadInt32(&s.workerPendingJobs)
+
+	logger.Infof("[Service.Close] pending jobs: %d", pendingJobs)
+
+	if pendingJobs <= 0 {
+		s.markAllWorkersDone()
 	}
 
-	// Something about this helps graceful shut downs, let's look into it more
-	time.Sleep(1 * time.Second)
+	<-s.allWorkersDone
+
+	logger.Infoln("[Service.Close] all workers have finished")
+
+	// Nothing is left to cancel
+	if subscriber, ok := s.getCancellationSubscriber().(DrainingSubscriber); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), SHUTDOWN_TIMEOUT)
+
+		if err := subscriber.Close(ctx); err != nil {
+			logger.Warnf("[Service.Close] failed to close the cancellation subscriber: %s", err)
+		}
+
+		cancel()
+	}
 
 	return nil
 }
 
+func (s *Service) getCancellationSubscriber() Subscriber {
+	s.pathTemplatesMu.Lock()
+	defer s.pathTemplatesMu.Unlock()
+
+	return s.cancellationSubscriber
+}
+
 func (s *Service) incrementWorkerPendingJobs() {
 	atomic.AddInt32(&s.workerPendingJobs, 1)
 }
 
 func (s *Service) decrementWorkerPendingJobs() {
-	atomic.AddInt32(&s.workerPendingJobs, -1)
+	if atomic.AddInt32(&s.workerPendingJobs, -1) <= 0 && !s.canAcceptWork() {
+		s.markAllWorkersDone()
+	}
+}
 
-	if s.closed && s.workerPendingJobs == 0 {
+func (s *Service) markAllWorkersDone() {
+	s.allWorkersDoneOnce.Do(func() {
 		close(s.allWorkersDone)
-	}
+	})
 }
 
 func (s *Service) Run() {
@@ -259,6 +548,10 @@ func (s *Service) Run() {
 
 	go s.healthManager.Run()
 
+	if cancellationSubscriber := s.getCancellationSubscriber(); cancellationSubscriber != nil {
+		cancellationSubscriber.Run()
+	}
+
 	s.subscriber.Run()
 
 	<-s.allWorkersDone
@@ -297,6 +590,8 @@ func NewService(serviceName string, publisher Publisher, subscriber Subscriber,
 			newPlatformHealthChecker(publisher),
 		},
 
+		inflightRequests: map[string]*RequestResponder{},
+
 		workerQuitChan: make(chan interface{}),
 		allWorkersDone: make(chan interface{}),
 	}, nil
@@ -315,6 +610,8 @@ func NewServiceWithResponder(serviceName string, publisher Publisher, subscriber
 			newPlatformHealthChecker(publisher),
 		},
 
+		inflightRequests: map[string]*RequestResponder{},
+
 		workerQuitChan: make(chan interface{}),
 		allWorkersDone: make(chan interface{}),
 	}, nil
@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"context"
 	"errors"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -51,6 +52,7 @@ func newMockPublisher() *mockPublisher {
 
 type mockSubscriber struct {
 	topicHandlers map[string][]ConsumerHandler
+	topicOptions  map[string]*SubscribeOptions
 	totalRunCalls int
 }
 
@@ -64,17 +66,50 @@ func (s *mockSubscriber) getTopicTotalHandlers() map[string]int {
 	return totalHandlers
 }
 
-func (s *mockSubscriber) Subscribe(topic string, handler ConsumerHandler) {
-	s.topicHandlers[topic] = append(s.topicHandlers[topic], handler)
+func (s *mockSubscriber) Subscribe(topic string, handler ConsumerHandler, options ...SubscribeOption) {
+	subscribeOptions := NewSubscribeOptions(options...)
+
+	for _, topic := range subscribeOptions.Topics(topic) {
+		s.topicHandlers[topic] = append(s.topicHandlers[topic], handler)
+		s.topicOptions[topic] = subscribeOptions
+	}
+}
+
+func (s *mockSubscriber) Unsubscribe(topic string) {
+	delete(s.topicHandlers, topic)
+	delete(s.topicOptions, topic)
 }
 
 func (s *mockSubscriber) Run() {
 	s.totalRunCalls += 1
 }
 
+// A subscriber that can be drained, recording the deadline it was given
+type mockDrainingSubscriber struct {
+	*mockSubscriber
+
+	totalCloseCalls int
+	closeDeadline   time.Time
+
+	// Close doesn't return until it's closed, when set
+	drained chan bool
+}
+
+func (s *mockDrainingSubscriber) Close(ctx context.Context) error {
+	s.totalCloseCalls += 1
+	s.closeDeadline, _ = ctx.Deadline()
+
+	if s.drained != nil {
+		<-s.drained
+	}
+
+	return nil
+}
+
 func newMockSubscriber() *mockSubscriber {
 	return &mockSubscriber{
 		topicHandlers: make(map[string][]ConsumerHandler),
+		topicOptions:  make(map[string]*SubscribeOptions),
 		totalRunCalls: 0,
 	}
 }
@@ -129,6 +164,76 @@ func TestServiceClose(t *testing.T) {
 		So(service.closed, ShouldBeTrue)
 	})
 
+	Convey("Closing a service should drain its subscriber first, and only once", t, func() {
+		mockSubscriber := &mockDrainingSubscriber{mockSubscriber: newMockSubscriber()}
+
+		service, err := NewServiceWithResponder("test-service", newMockPublisher(), mockSubscriber, nil, newMockResponder())
+		So(err, ShouldBeNil)
+
+		So(service.Close(), ShouldBeNil)
+		So(service.Close(), ShouldBeNil)
+
+		So(mockSubscriber.totalCloseCalls, ShouldEqual, 1)
+		So(mockSubscriber.closeDeadline, ShouldHappenWithin, SHUTDOWN_TIMEOUT, time.Now())
+	})
+
+	Convey("Closing a service should not hold its lock while the subscriber drains", t, func() {
+		mockSubscriber := &mockDrainingSubscriber{
+			mockSubscriber: newMockSubscriber(),
+			drained:        make(chan bool),
+		}
+
+		service, err := NewServiceWithResponder("test-service", newMockPublisher(), mockSubscriber, nil, newMockResponder())
+		So(err, ShouldBeNil)
+
+		firstClosed := make(chan bool)
+		go func() {
+			service.Close()
+			close(firstClosed)
+		}()
+
+		locked := make(chan bool)
+		go func() {
+			for {
+				service.mu.Lock()
+				closed := service.closed
+				service.mu.Unlock()
+
+				if closed {
+					close(locked)
+					return
+				}
+
+				time.Sleep(time.Millisecond)
+			}
+		}()
+
+		select {
+		case <-locked:
+		case <-time.After(time.Second):
+			t.Fatal("the lock was held while draining the subscriber")
+		}
+
+		secondClosed := make(chan bool)
+		go func() {
+			service.Close()
+			close(secondClosed)
+		}()
+
+		select {
+		case <-secondClosed:
+			t.Fatal("a second close returned before the service was shut down")
+		case <-time.After(10 * time.Millisecond):
+		}
+
+		close(mockSubscriber.drained)
+
+		<-firstClosed
+		<-secondClosed
+
+		So(mockSubscriber.totalCloseCalls, ShouldEqual, 1)
+	})
+
 	Convey("A service should not be able to close until all if its work is done", t, func() {
 		mockPublisher := newMockPublisher()
 		mockSubscriber := newMockSubscriber()
@@ -186,6 +291,7 @@ func TestServiceHandler(t *testing.T) {
 		So(mockPublisher.mockPublishes, ShouldResemble, []mockPublish{})
 		So(mockSubscriber.getTopicTotalHandlers(), ShouldResemble, map[string]int{
 			"microservice-testing": 1,
+			"microservice.testing": 1,
 		})
 
 		requestBytes, _ := Marshal(&Request{
@@ -220,6 +326,7 @@ func TestServiceHandler(t *testing.T) {
 		So(mockPublisher.mockPublishes, ShouldResemble, []mockPublish{})
 		So(mockSubscriber.getTopicTotalHandlers(), ShouldResemble, map[string]int{
 			"microservice-testing": 1,
+			"microservice.testing": 1,
 		})
 
 		So(totalHandlerCalls, ShouldEqual, 0)
@@ -233,6 +340,11 @@ func TestServiceHandler(t *testing.T) {
 
 		So(len(mockResponder.requests), ShouldEqual, 1)
 		So(mockResponder.requests[0].Routing.RouteTo[0].GetUri(), ShouldEqual, "resource:///platform/reply/error")
+
+		platformError := NewPlatformErrorFromResponse(mockResponder.requests[0])
+		So(platformError.Code, ShouldEqual, Error_INTERNAL)
+		So(platformError.Details["path"], ShouldEqual, "testing")
+		So(platformError.Details["reason"], ShouldEqual, "YAY FAILURE!")
 	})
 
 	Convey("Synchronously calling a handler after closing the service should not actually call the handler", t, func() {
@@ -258,6 +370,7 @@ func TestServiceHandler(t *testing.T) {
 		So(mockPublisher.mockPublishes, ShouldResemble, []mockPublish{})
 		So(mockSubscriber.getTopicTotalHandlers(), ShouldResemble, map[string]int{
 			"microservice-testing": 1,
+			"microservice.testing": 1,
 		})
 
 		So(totalHandlerCalls, ShouldEqual, 0)
@@ -301,6 +414,7 @@ func TestServiceHandler(t *testing.T) {
 		So(mockPublisher.mockPublishes, ShouldResemble, []mockPublish{})
 		So(mockSubscriber.getTopicTotalHandlers(), ShouldResemble, map[string]int{
 			"microservice-testing": 1,
+			"microservice.testing": 1,
 		})
 
 		firstHandlerEvents := make(chan string)
@@ -366,6 +480,229 @@ func TestServiceHandler(t *testing.T) {
 	})
 }
 
+func TestServiceHandlerPathTemplate(t *testing.T) {
+	Convey("A templated handler should bind a wildcard and receive the path params", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+		mockResponder := newMockResponder()
+
+		service, err := NewServiceWithResponder("test-service", mockPublisher, mockSubscriber, nil, mockResponder)
+		So(err, ShouldBeNil)
+
+		userId := ""
+
+		service.AddHandler("/users/{id}/orders", HandlerFunc(func(responder Responder, request *Request) {
+			userId = PathParam(request, "id")
+
+			responder.Respond(&Request{
+				Routing:   RouteToUri("resource:///teltech/reply/orders"),
+				Completed: Bool(true),
+			})
+		}))
+
+		So(mockSubscriber.getTopicTotalHandlers(), ShouldResemble, map[string]int{
+			"microservice.users.*.orders": 1,
+		})
+
+		requestBytes, _ := Marshal(&Request{
+			Routing: RouteToUri("microservice:///users/42/orders"),
+		})
+
+		So(mockSubscriber.topicHandlers["microservice.users.*.orders"][0].HandleMessage(requestBytes), ShouldBeNil)
+		So(userId, ShouldEqual, "42")
+		So(len(mockResponder.requests), ShouldEqual, 1)
+	})
+
+	Convey("Only the handler with the most specific path should serve a request that several handlers match", t, func() {
+		mockSubscriber := newMockSubscriber()
+
+		service, err := NewServiceWithResponder("test-service", newMockPublisher(), mockSubscriber, nil, newMockResponder())
+		So(err, ShouldBeNil)
+
+		served := []string{}
+
+		for _, path := range []string{"/users/{id}", "/users/me", "/users/*"} {
+			path := path
+
+			service.AddHandler(path, HandlerFunc(func(responder Responder, request *Request) {
+				served = append(served, path)
+			}))
+		}
+
+		// Every subscription bound to a key that matches gets the request
+		handle := func(uri string, topics ...string) {
+			requestBytes, _ := Marshal(&Request{
+				Routing: RouteToUri(uri),
+			})
+
+			for _, topic := range topics {
+				for _, handler := range mockSubscriber.topicHandlers[topic] {
+					So(handler.HandleMessage(requestBytes), ShouldBeNil)
+				}
+			}
+		}
+
+		handle("microservice:///users/me", "microservice.users.*", "microservice.users.me")
+		So(served, ShouldResemble, []string{"/users/me"})
+
+		handle("microservice:///users/42", "microservice.users.*")
+		So(served, ShouldResemble, []string{"/users/me", "/users/{id}"})
+	})
+}
+
+func TestServiceHandlerCancellation(t *testing.T) {
+	Convey("A cancellation for an in-flight request should cancel the handler's context", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+		mockResponder := newMockResponder()
+
+		service, err := NewServiceWithResponder("test-service", mockPublisher, mockSubscriber, nil, mockResponder)
+		So(err, ShouldBeNil)
+
+		cancellationSubscriber := newMockSubscriber()
+		service.SetCancellationSubscriber(cancellationSubscriber)
+
+		handlerStarted := make(chan interface{})
+		handlerCancelled := make(chan interface{})
+
+		service.AddHandler("testing", HandlerFunc(func(responder Responder, request *Request) {
+			close(handlerStarted)
+
+			select {
+			case <-ResponderContext(responder).Done():
+				close(handlerCancelled)
+			case <-time.After(time.Second):
+			}
+		}))
+
+		requestBytes, _ := Marshal(&Request{
+			Uuid:    String("request-1"),
+			Routing: RouteToUri("microservice:///teltech/get/foobar"),
+		})
+
+		cancellationBytes, _ := Marshal(&Request{
+			Uuid:    String("request-1"),
+			Routing: RouteToUri("resource:///platform/cancel"),
+		})
+
+		go mockSubscriber.topicHandlers["microservice-testing"][0].HandleMessage(requestBytes)
+
+		<-handlerStarted
+
+		So(cancellationSubscriber.topicHandlers["cancellation.microservice-testing"][0].HandleMessage(cancellationBytes), ShouldBeNil)
+
+		select {
+		case <-handlerCancelled:
+		case <-time.After(500 * time.Millisecond):
+			t.Error("The handler's context was not cancelled")
+		}
+	})
+
+	Convey("A cancellation for an unknown request should be ignored", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+		mockResponder := newMockResponder()
+
+		service, err := NewServiceWithResponder("test-service", mockPublisher, mockSubscriber, nil, mockResponder)
+		So(err, ShouldBeNil)
+
+		totalHandlerCalls := 0
+
+		service.AddHandler("testing", HandlerFunc(func(responder Responder, request *Request) {
+			totalHandlerCalls += 1
+		}))
+
+		// Handlers added before the subscriber is set get their cancellations too
+		cancellationSubscriber := newMockSubscriber()
+		service.SetCancellationSubscriber(cancellationSubscriber)
+
+		So(cancellationSubscriber.getTopicTotalHandlers(), ShouldResemble, map[string]int{
+			"cancellation.microservice.testing": 1,
+			"cancellation.microservice-testing": 1,
+		})
+
+		cancellationBytes, _ := Marshal(&Request{
+			Uuid:    String("request-1"),
+			Routing: RouteToUri("resource:///platform/cancel"),
+		})
+
+		So(cancellationSubscriber.topicHandlers["cancellation.microservice-testing"][0].HandleMessage(cancellationBytes), ShouldBeNil)
+		So(totalHandlerCalls, ShouldEqual, 0)
+		So(len(mockResponder.requests), ShouldEqual, 0)
+	})
+}
+
+func TestServiceHandlerDeadline(t *testing.T) {
+	Convey("A request whose deadline has passed should be answered with an error instead of being handled", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+		mockResponder := newMockResponder()
+
+		service, err := NewServiceWithResponder("test-service", mockPublisher, mockSubscriber, nil, mockResponder)
+		So(err, ShouldBeNil)
+
+		totalHandlerCalls := 0
+
+		service.AddHandler("testing", HandlerFunc(func(responder Responder, request *Request) {
+			totalHandlerCalls += 1
+		}))
+
+		request := &Request{
+			Routing: RouteToUri("microservice:///teltech/get/foobar"),
+		}
+		SetRequestDeadline(request, time.Now().Add(-time.Second))
+
+		requestBytes, _ := Marshal(request)
+
+		So(mockSubscriber.topicHandlers["microservice-testing"][0].HandleMessage(requestBytes), ShouldBeNil)
+		So(totalHandlerCalls, ShouldEqual, 0)
+		So(service.workerPendingJobs, ShouldEqual, 0)
+
+		So(len(mockResponder.requests), ShouldEqual, 1)
+		So(mockResponder.requests[0].Routing.RouteTo[0].GetUri(), ShouldEqual, "resource:///platform/reply/error")
+		So(mockResponder.requests[0].GetCompleted(), ShouldBeTrue)
+
+		platformError := NewPlatformErrorFromResponse(mockResponder.requests[0])
+		So(platformError.Code, ShouldEqual, Error_DEADLINE_EXCEEDED)
+		So(platformError.Message, ShouldStartWith, "deadline exceeded")
+	})
+
+	Convey("A request whose deadline has not passed should be handled with the deadline on its context", t, func() {
+		mockPublisher := newMockPublisher()
+		mockSubscriber := newMockSubscriber()
+		mockResponder := newMockResponder()
+
+		service, err := NewServiceWithResponder("test-service", mockPublisher, mockSubscriber, nil, mockResponder)
+		So(err, ShouldBeNil)
+
+		var handlerDeadline time.Time
+
+		service.AddHandler("testing", HandlerFunc(func(responder Responder, request *Request) {
+			handlerDeadline, _ = ResponderContext(responder).Deadline()
+
+			responder.Respond(&Request{
+				Routing:   RouteToUri("resource:///teltech/reply/foobar"),
+				Completed: Bool(true),
+			})
+		}))
+
+		deadline := time.Now().Add(time.Minute)
+
+		request := &Request{
+			Routing: RouteToUri("microservice:///teltech/get/foobar"),
+		}
+		SetRequestDeadline(request, deadline)
+
+		requestBytes, _ := Marshal(request)
+
+		So(mockSubscriber.topicHandlers["microservice-testing"][0].HandleMessage(requestBytes), ShouldBeNil)
+		So(handlerDeadline.Equal(deadline), ShouldBeTrue)
+
+		So(len(mockResponder.requests), ShouldEqual, 1)
+		So(mockResponder.requests[0].Routing.RouteTo[0].GetUri(), ShouldEqual, "resource:///teltech/reply/foobar")
+	})
+}
+
 func TestServiceListener(t *testing.T) {
 	Convey("Ensure that adding a listener and calling it works properly", t, func() {
 		mockPublisher := newMockPublisher()
@@ -425,6 +762,44 @@ func TestServiceListener(t *testing.T) {
 		So(len(mockPublisher.mockPublishes), ShouldEqual, 1)
 		So(mockPublisher.mockPublishes[0].topic, ShouldEqual, "panic.listener.testing")
 	})
+
+	Convey("Handlers and listeners should subscribe with their own worker pool sizes", t, func() {
+		mockSubscriber := newMockSubscriber()
+
+		service, err := NewServiceWithResponder("test-service", newMockPublisher(), mockSubscriber, nil, newMockResponder())
+		So(err, ShouldBeNil)
+
+		service.AddListener("testing", ConsumerHandlerFunc(func(body []byte) error {
+			return nil
+		}), WithListenerSubscribeOptions(WithConcurrency(5)))
+
+		service.AddHandler("testing", HandlerFunc(func(responder Responder, request *Request) {
+		}), WithHandlerSubscribeOptions(WithAdaptiveConcurrency(2, 20)))
+
+		So(mockSubscriber.topicOptions["testing"], ShouldResemble, &SubscribeOptions{
+			Concurrency: 5,
+		})
+		So(mockSubscriber.topicOptions["microservice.testing"], ShouldResemble, &SubscribeOptions{
+			Adaptive:         true,
+			MinConcurrency:   2,
+			MaxConcurrency:   20,
+			AdditionalTopics: []string{"microservice-testing"},
+		})
+	})
+
+	Convey("Listeners should be able to handle their publishes in order", t, func() {
+		mockSubscriber := newMockSubscriber()
+
+		service, err := NewServiceWithResponder("test-service", newMockPublisher(), mockSubscriber, nil, newMockResponder())
+		So(err, ShouldBeNil)
+
+		service.AddListener("accounts.*", ConsumerHandlerFunc(func(body []byte) error {
+			return nil
+		}), WithListenerSubscribeOptions(WithOrdering(16, HeaderOrderingKey("account_id"))))
+
+		So(mockSubscriber.topicOptions["accounts.*"].Ordered(), ShouldBeTrue)
+		So(mockSubscriber.topicOptions["accounts.*"].Lanes, ShouldEqual, 16)
+	})
 }
 
 func TestServiceRun(t *testing.T) {
@@ -1,10 +1,169 @@
 package platform
 
-import "sync"
+import (
+	"context"
+	"fmt"
+	"hash/fnv"
+	"sync"
+	"time"
+)
 
+// Subscribe and Unsubscribe can be called before and after Run, the topic is
+// bound or unbound right away when the subscriber is already running.
+// Unsubscribe removes every handler of the topic and returns once their
+// in-flight messages have been handled.
 type Subscriber interface {
 	Run()
-	Subscribe(topic string, handler ConsumerHandler)
+	Subscribe(topic string, handler ConsumerHandler, options ...SubscribeOption)
+	Unsubscribe(topic string)
+}
+
+// DrainingSubscriber is implemented by the subscribers that can shut down
+// gracefully. Close stops them from receiving new messages and waits for the
+// ones being handled until the context is done. What's left unhandled by then
+// only goes back to the broker if the subscriber settles messages after
+// handling them, such as the AMQP subscriber in at-least-once mode.
+type DrainingSubscriber interface {
+	Subscriber
+	Close(ctx context.Context) error
+}
+
+// SubscribeOption configures a single subscription, mostly the pool of workers
+// handling it. Subscribers that don't have worker pools ignore those options.
+type SubscribeOption func(*SubscribeOptions)
+
+type SubscribeOptions struct {
+	// How many messages of the subscription are handled at the same time, zero
+	// leaves it up to the subscriber
+	Concurrency int
+
+	// Adaptive pools grow and shrink between their min and max concurrency
+	// depending on how much work is waiting on them
+	Adaptive       bool
+	MinConcurrency int
+	MaxConcurrency int
+
+	// Ordered subscriptions handle their messages on a fixed number of lanes
+	// instead, picked by the message's ordering key
+	Lanes       int
+	OrderingKey OrderingKey
+
+	// Further topics bound for the subscription, their messages are handled by
+	// the same workers as the ones of its own topic
+	AdditionalTopics []string
+}
+
+func (o *SubscribeOptions) Ordered() bool {
+	return o.Lanes > 0 && o.OrderingKey != nil
+}
+
+// Topics lists every topic bound for a subscription to the topic
+func (o *SubscribeOptions) Topics(topic string) []string {
+	return append([]string{topic}, o.AdditionalTopics...)
+}
+
+// Lane picks the lane of an ordered subscription that handles the message,
+// messages with the same ordering key always get the same lane.
+func (o *SubscribeOptions) Lane(body []byte, metadata *MessageMetadata) int {
+	hash := fnv.New32a()
+	hash.Write([]byte(o.OrderingKey(body, metadata)))
+
+	return int(hash.Sum32() % uint32(o.Lanes))
+}
+
+// OrderingKey tells which messages have to be handled in the order they were
+// delivered, the ones that share the same key.
+type OrderingKey func(body []byte, metadata *MessageMetadata) string
+
+// HeaderOrderingKey orders the messages by the value of one of their headers,
+// the messages without it share a single lane.
+func HeaderOrderingKey(header string) OrderingKey {
+	return func(body []byte, metadata *MessageMetadata) string {
+		value, exists := metadata.Headers[header]
+		if !exists {
+			return ""
+		}
+
+		return fmt.Sprint(value)
+	}
+}
+
+// WithConcurrency handles up to concurrency messages of the subscription at
+// the same time.
+func WithConcurrency(concurrency int) SubscribeOption {
+	return func(options *SubscribeOptions) {
+		options.Concurrency = concurrency
+		options.Adaptive = false
+	}
+}
+
+// WithAdaptiveConcurrency starts the subscription's pool at min workers and
+// lets the subscriber grow it up to max workers while messages are kept
+// waiting, shrinking it back once the workers sit idle.
+func WithAdaptiveConcurrency(min, max int) SubscribeOption {
+	return func(options *SubscribeOptions) {
+		if min < 1 {
+			min = 1
+		}
+
+		if max < min {
+			max = min
+		}
+
+		options.Adaptive = true
+		options.MinConcurrency = min
+		options.MaxConcurrency = max
+	}
+}
+
+// WithOrdering handles the messages on a fixed number of lanes that each handle
+// one message at a time. Messages with the same key always go down the same
+// lane, so they are handled in the order they were delivered while messages
+// with other keys are handled in parallel. The lanes replace the pool's
+// concurrency. Order only holds within a single subscriber and for messages
+// that aren't retried or requeued.
+func WithOrdering(lanes int, key OrderingKey) SubscribeOption {
+	return func(options *SubscribeOptions) {
+		if lanes < 1 {
+			lanes = 1
+		}
+
+		options.Lanes = lanes
+		options.OrderingKey = key
+	}
+}
+
+// WithAdditionalTopics binds more topics for the subscription, so that their
+// messages share its handler and its workers. Unsubscribing from the topic the
+// subscription was made with unbinds them as well.
+func WithAdditionalTopics(topics ...string) SubscribeOption {
+	return func(options *SubscribeOptions) {
+		options.AdditionalTopics = append(options.AdditionalTopics, topics...)
+	}
+}
+
+func NewSubscribeOptions(options ...SubscribeOption) *SubscribeOptions {
+	subscribeOptions := &SubscribeOptions{}
+	for _, option := range options {
+		option(subscribeOptions)
+	}
+
+	return subscribeOptions
+}
+
+// WorkerPool describes the workers handling a subscription at the moment
+type WorkerPool struct {
+	Topic   string
+	Size    int
+	Busy    int
+	MinSize int
+	MaxSize int
+}
+
+// WorkerPoolReporter is implemented by the subscribers that can tell how their
+// worker pools are sized, for monitoring.
+type WorkerPoolReporter interface {
+	WorkerPools() []WorkerPool
 }
 
 type ConsumerHandler interface {
@@ -17,6 +176,45 @@ func (handlerFunc ConsumerHandlerFunc) HandleMessage(p []byte) error {
 	return handlerFunc(p)
 }
 
+// MessageMetadata describes how a message was delivered, as far as the
+// subscriber's transport knows. Fields the transport doesn't know are left
+// empty.
+type MessageMetadata struct {
+	MessageId   string
+	RoutingKey  string
+	Headers     map[string]interface{}
+	Redelivered bool
+	Timestamp   time.Time
+}
+
+// MetadataConsumerHandler is implemented by the consumer handlers that want
+// the message's metadata along with its body. Subscribers call
+// HandleMessageWithMetadata instead of HandleMessage on them.
+type MetadataConsumerHandler interface {
+	ConsumerHandler
+	HandleMessageWithMetadata(body []byte, metadata *MessageMetadata) error
+}
+
+type MetadataConsumerHandlerFunc func(body []byte, metadata *MessageMetadata) error
+
+func (handlerFunc MetadataConsumerHandlerFunc) HandleMessage(body []byte) error {
+	return handlerFunc(body, &MessageMetadata{})
+}
+
+func (handlerFunc MetadataConsumerHandlerFunc) HandleMessageWithMetadata(body []byte, metadata *MessageMetadata) error {
+	return handlerFunc(body, metadata)
+}
+
+// HandleMessageWithMetadata hands the metadata over along with the body if
+// the handler wants it, which is how subscribers and middleware pass it on.
+func HandleMessageWithMetadata(handler ConsumerHandler, body []byte, metadata *MessageMetadata) error {
+	if metadataHandler, ok := handler.(MetadataConsumerHandler); ok {
+		return metadataHandler.HandleMessageWithMetadata(body, metadata)
+	}
+
+	return handler.HandleMessage(body)
+}
+
 type MultiSubscriber struct {
 	subscribers []Subscriber
 }
@@ -40,10 +238,57 @@ func (s *MultiSubscriber) Run() {
 	wg.Wait()
 }
 
-func (s *MultiSubscriber) Subscribe(topic string, handler ConsumerHandler) {
+func (s *MultiSubscriber) Subscribe(topic string, handler ConsumerHandler, options ...SubscribeOption) {
+	for i := range s.subscribers {
+		s.subscribers[i].Subscribe(topic, handler, options...)
+	}
+}
+
+func (s *MultiSubscriber) Unsubscribe(topic string) {
+	for i := range s.subscribers {
+		s.subscribers[i].Unsubscribe(topic)
+	}
+}
+
+// Close drains every subscriber that can be drained at the same time, returning
+// the first error any of them returned.
+func (s *MultiSubscriber) Close(ctx context.Context) error {
+	errs := make(chan error, len(s.subscribers))
+
 	for i := range s.subscribers {
-		s.subscribers[i].Subscribe(topic, handler)
+		subscriber, ok := s.subscribers[i].(DrainingSubscriber)
+		if !ok {
+			errs <- nil
+			continue
+		}
+
+		go func() {
+			errs <- subscriber.Close(ctx)
+		}()
 	}
+
+	var closeErr error
+
+	for range s.subscribers {
+		if err := <-errs; err != nil && closeErr == nil {
+			closeErr = err
+		}
+	}
+
+	return closeErr
+}
+
+// WorkerPools lists the worker pools of every subscriber that reports them
+func (s *MultiSubscriber) WorkerPools() []WorkerPool {
+	workerPools := []WorkerPool{}
+
+	for i := range s.subscribers {
+		if reporter, ok := s.subscribers[i].(WorkerPoolReporter); ok {
+			workerPools = append(workerPools, reporter.WorkerPools()...)
+		}
+	}
+
+	return workerPools
 }
 
 func NewMultiSubscriber(subscribers []Subscriber) *MultiSubscriber {
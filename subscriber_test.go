@@ -1,6 +1,7 @@
 package platform
 
 import (
+	"context"
 	"testing"
 
 	. "github.com/smartystreets/goconvey/convey"
@@ -35,5 +36,53 @@ func TestMultiSubscriber(t *testing.T) {
 		So(mockSubscriber2.getTopicTotalHandlers(), ShouldResemble, map[string]int{
 			"testing": 1,
 		})
+
+		multiSubscriber.Unsubscribe("testing")
+
+		So(mockSubscriber1.getTopicTotalHandlers(), ShouldResemble, map[string]int{})
+		So(mockSubscriber2.getTopicTotalHandlers(), ShouldResemble, map[string]int{})
+	})
+
+	Convey("Messages with the same ordering key should always get the same lane", t, func() {
+		options := NewSubscribeOptions(WithOrdering(8, HeaderOrderingKey("account")))
+		So(options.Ordered(), ShouldBeTrue)
+
+		lane := options.Lane([]byte("a"), &MessageMetadata{Headers: map[string]interface{}{"account": 42}})
+		So(lane, ShouldBeBetweenOrEqual, 0, 7)
+
+		for i := 0; i < 10; i++ {
+			So(options.Lane([]byte{byte(i)}, &MessageMetadata{Headers: map[string]interface{}{"account": "42"}}), ShouldEqual, lane)
+		}
+
+		So(NewSubscribeOptions(WithConcurrency(4)).Ordered(), ShouldBeFalse)
+	})
+
+	Convey("A multi subscriber should drain the subscribers that can be drained", t, func() {
+		mockSubscriber1 := &mockDrainingSubscriber{mockSubscriber: newMockSubscriber()}
+		mockSubscriber2 := newMockSubscriber()
+
+		multiSubscriber := NewMultiSubscriber([]Subscriber{mockSubscriber1, mockSubscriber2})
+
+		So(multiSubscriber.Close(context.Background()), ShouldBeNil)
+		So(mockSubscriber1.totalCloseCalls, ShouldEqual, 1)
+	})
+
+	Convey("A multi subscriber should pass the subscribe options to all subscribers", t, func() {
+		mockSubscriber1 := newMockSubscriber()
+		mockSubscriber2 := newMockSubscriber()
+
+		multiSubscriber := NewMultiSubscriber([]Subscriber{mockSubscriber1, mockSubscriber2})
+
+		multiSubscriber.Subscribe("testing", ConsumerHandlerFunc(func(body []byte) error {
+			return nil
+		}), WithAdaptiveConcurrency(0, -1))
+
+		for _, mockSubscriber := range []*mockSubscriber{mockSubscriber1, mockSubscriber2} {
+			So(mockSubscriber.topicOptions["testing"], ShouldResemble, &SubscribeOptions{
+				Adaptive:       true,
+				MinConcurrency: 1,
+				MaxConcurrency: 1,
+			})
+		}
 	})
 }
@@ -0,0 +1,38 @@
+package platform
+
+import "strings"
+
+// TopicMatches reports whether a routing key matches a binding pattern using
+// the AMQP topic exchange rules, where words are separated by dots, * matches
+// exactly one word and # matches zero or more words.
+func TopicMatches(pattern, routingKey string) bool {
+	return topicWordsMatch(strings.Split(pattern, "."), strings.Split(routingKey, "."))
+}
+
+func topicWordsMatch(patternWords, routingKeyWords []string) bool {
+	if len(patternWords) <= 0 {
+		return len(routingKeyWords) <= 0
+	}
+
+	switch patternWords[0] {
+	case "#":
+		// Collapse consecutive hashes, they match the same thing as a single one
+		if len(patternWords) > 1 && patternWords[1] == "#" {
+			return topicWordsMatch(patternWords[1:], routingKeyWords)
+		}
+
+		for i := 0; i <= len(routingKeyWords); i++ {
+			if topicWordsMatch(patternWords[1:], routingKeyWords[i:]) {
+				return true
+			}
+		}
+
+		return false
+
+	case "*":
+		return len(routingKeyWords) > 0 && topicWordsMatch(patternWords[1:], routingKeyWords[1:])
+
+	default:
+		return len(routingKeyWords) > 0 && patternWords[0] == routingKeyWords[0] && topicWordsMatch(patternWords[1:], routingKeyWords[1:])
+	}
+}
@@ -0,0 +1,36 @@
+package platform
+
+import (
+	"testing"
+
+	. "github.com/smartystreets/goconvey/convey"
+)
+
+func TestTopicMatches(t *testing.T) {
+	Convey("Topic matching should follow the AMQP topic exchange rules", t, func() {
+		for _, testCase := range []struct {
+			pattern    string
+			routingKey string
+			matches    bool
+		}{
+			{"orders.created", "orders.created", true},
+			{"orders.created", "orders.updated", false},
+			{"orders.*", "orders.created", true},
+			{"orders.*", "orders", false},
+			{"orders.*", "orders.created.today", false},
+			{"*.created", "orders.created", true},
+			{"#", "orders.created", true},
+			{"#", "", true},
+			{"orders.#", "orders", true},
+			{"orders.#", "orders.created.today", true},
+			{"events.#.created", "events.created", true},
+			{"events.#.created", "events.user.account.created", true},
+			{"events.#.created", "events.user.deleted", false},
+			{"#.#.created", "events.created", true},
+			{"microservice.users.*.orders", "microservice.users.42.orders", true},
+			{"microservice-/teltech/get/foobar", "microservice-/teltech/get/foobar", true},
+		} {
+			So(TopicMatches(testCase.pattern, testCase.routingKey), ShouldEqual, testCase.matches)
+		}
+	})
+}
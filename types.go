@@ -17,6 +17,7 @@ var (
 	Float64 = proto.Float64
 	String  = proto.String
 
+	Clone     = proto.Clone
 	Marshal   = proto.Marshal
 	Unmarshal = proto.Unmarshal
 )